@@ -0,0 +1,156 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"fmt"
+	"io"
+
+	"filippo.io/edwards25519"
+)
+
+// BatchVerifier checks many signatures with a single multi scalar
+// multiplication, which is roughly twice as fast as verifying them one by
+// one. It applies the rule of Signature.Verify, so but for a negligible
+// chance a batch verifies exactly when each of its signatures does. A
+// failed batch only tells that at least one signature is invalid, use
+// Failed to find out which.
+type BatchVerifier struct {
+	entries []batchEntry
+}
+
+type batchEntry struct {
+	pubKey []byte
+	msg    []byte
+	sig    []byte
+}
+
+func NewBatchVerifier() *BatchVerifier {
+	return &BatchVerifier{
+		entries: []batchEntry{},
+	}
+}
+
+func (b *BatchVerifier) Add(pubKey *PublicKeys, msg []byte, sig *Signature) {
+	b.entries = append(b.entries, batchEntry{
+		pubKey: pubKey.Bytes(),
+		msg:    msg,
+		sig:    sig.Bytes(),
+	})
+}
+
+func (b *BatchVerifier) Len() int {
+	return len(b.entries)
+}
+
+// Verify reports whether all signatures of the batch are valid.
+func (b *BatchVerifier) Verify() bool {
+	if len(b.entries) == 0 {
+		return true
+	}
+	var (
+		n       = len(b.entries)
+		scalars = make([]*edwards25519.Scalar, 0, 2*n+1)
+		points  = make([]*edwards25519.Point, 0, 2*n+1)
+		sumS    = edwards25519.NewScalar()
+	)
+	for _, e := range b.entries {
+		A, R, s, k, err := decodeSignature(e.pubKey, e.msg, e.sig)
+		if err != nil {
+			return false
+		}
+
+		z, err := randomBatchScalar()
+		if err != nil {
+			return false
+		}
+
+		// [8][z]R + [8][z*k]A = [8][z*s]B for every signature
+		sumS.MultiplyAdd(z, s, sumS)
+		scalars = append(scalars, z, new(edwards25519.Scalar).Multiply(z, k))
+		points = append(points, R, A)
+	}
+	scalars = append(scalars, sumS.Negate(sumS))
+	points = append(points, edwards25519.NewGeneratorPoint())
+
+	check := new(edwards25519.Point).VarTimeMultiScalarMult(scalars, points)
+	check.MultByCofactor(check)
+	return check.Equal(edwards25519.NewIdentityPoint()) == 1
+}
+
+// Failed returns the indexes of the signatures that do not verify on their own.
+func (b *BatchVerifier) Failed() []int {
+	failed := []int{}
+	for i, e := range b.entries {
+		if !verify(e.pubKey, e.msg, e.sig) {
+			failed = append(failed, i)
+		}
+	}
+	return failed
+}
+
+// verify checks the cofactored equation [8][s]B = [8]R + [8][k]A, the rule
+// of ZIP-215 except that A and R have to be canonically encoded and must not
+// be of small order, which would make the equation hold for any message.
+// Unlike the cofactorless equation it can be checked in batches with the
+// same outcome.
+func verify(pubKey []byte, msg []byte, sig []byte) bool {
+	if len(pubKey) != PubKeyLen || len(sig) != SignatureLen {
+		return false
+	}
+	A, R, s, k, err := decodeSignature(pubKey, msg, sig)
+	if err != nil {
+		return false
+	}
+	// [s]B - [k]A - R
+	check := new(edwards25519.Point).VarTimeDoubleScalarBaseMult(k, new(edwards25519.Point).Negate(A), s)
+	check.Subtract(check, R)
+	check.MultByCofactor(check)
+	return check.Equal(edwards25519.NewIdentityPoint()) == 1
+}
+
+// decodeSignature returns the public key A, the commitment R and the scalar
+// s of a signature and its challenge k = H(R || A || msg).
+func decodeSignature(pubKey []byte, msg []byte, sig []byte) (A, R *edwards25519.Point, s, k *edwards25519.Scalar, err error) {
+	if A, err = decodePoint(pubKey); err != nil {
+		return
+	}
+	if R, err = decodePoint(sig[:32]); err != nil {
+		return
+	}
+	if s, err = new(edwards25519.Scalar).SetCanonicalBytes(sig[32:]); err != nil {
+		return
+	}
+	h := sha512.New()
+	h.Write(sig[:32])
+	h.Write(pubKey)
+	h.Write(msg)
+	k, err = new(edwards25519.Scalar).SetUniformBytes(h.Sum(nil))
+	return
+}
+
+// decodePoint decodes a canonically encoded point that is not of small
+// order.
+func decodePoint(b []byte) (*edwards25519.Point, error) {
+	p, err := new(edwards25519.Point).SetBytes(b)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(p.Bytes(), b) {
+		return nil, fmt.Errorf("non canonical point encoding")
+	}
+	if new(edwards25519.Point).MultByCofactor(p).Equal(edwards25519.NewIdentityPoint()) == 1 {
+		return nil, fmt.Errorf("point of small order")
+	}
+	return p, nil
+}
+
+// random 128 bit scalar, enough to make forging a batch infeasible
+func randomBatchScalar() (*edwards25519.Scalar, error) {
+	b := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, b[:16]); err != nil {
+		return nil, err
+	}
+	return new(edwards25519.Scalar).SetCanonicalBytes(b)
+}
@@ -0,0 +1,180 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"filippo.io/edwards25519"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBatch(n int) *BatchVerifier {
+	batch := NewBatchVerifier()
+	for i := 0; i < n; i++ {
+		privKey := GeneratePrivateKey()
+		msg := []byte(fmt.Sprintf("message %d", i))
+		batch.Add(privKey.Public(), msg, privKey.Sign(msg))
+	}
+	return batch
+}
+
+func TestBatchVerify(t *testing.T) {
+	assert.True(t, NewBatchVerifier().Verify())
+
+	for _, n := range []int{1, 2, 64} {
+		batch := newTestBatch(n)
+		assert.Equal(t, n, batch.Len())
+		assert.True(t, batch.Verify())
+		assert.Empty(t, batch.Failed())
+	}
+}
+
+func TestBatchVerifyInvalid(t *testing.T) {
+	batch := newTestBatch(32)
+	privKey := GeneratePrivateKey()
+	batch.Add(privKey.Public(), []byte("foo"), privKey.Sign([]byte("bar")))
+	for i := 0; i < 8; i++ {
+		privKey := GeneratePrivateKey()
+		batch.Add(privKey.Public(), []byte("foo"), privKey.Sign([]byte("foo")))
+	}
+
+	assert.False(t, batch.Verify())
+	assert.Equal(t, []int{32}, batch.Failed())
+}
+
+func TestBatchVerifyMalformed(t *testing.T) {
+	var (
+		batch   = newTestBatch(4)
+		privKey = GeneratePrivateKey()
+		msg     = []byte("foo")
+		sig     = privKey.Sign(msg).Bytes()
+	)
+
+	// a non canonical s is rejected by Signature.Verify as well
+	malformed := make([]byte, SignatureLen)
+	copy(malformed, sig)
+	for i := 32; i < SignatureLen; i++ {
+		malformed[i] = 0xff
+	}
+	batch.Add(privKey.Public(), msg, SignatureFromBytes(malformed))
+
+	assert.False(t, batch.Verify())
+	assert.Equal(t, []int{4}, batch.Failed())
+}
+
+func randomScalar(t *testing.T) *edwards25519.Scalar {
+	b := make([]byte, 64)
+	_, err := rand.Read(b)
+	require.Nil(t, err)
+	s, err := new(edwards25519.Scalar).SetUniformBytes(b)
+	require.Nil(t, err)
+	return s
+}
+
+// torsionSignature returns a valid signature of msg by a public key with a
+// small order component, which only the cofactored equation accepts for
+// sure.
+func torsionSignature(t *testing.T, msg []byte) (*PublicKeys, *Signature) {
+	T, err := new(edwards25519.Point).SetBytes(mustDecodeHex(t, "26e8958fc2b227b045c3f489f2ef98f0d5dfac05d3c63339b13802886d53fc05"))
+	require.Nil(t, err)
+	require.Equal(t, 1, new(edwards25519.Point).MultByCofactor(T).Equal(edwards25519.NewIdentityPoint()))
+
+	var (
+		a = randomScalar(t)
+		r = randomScalar(t)
+		A = new(edwards25519.Point).Add(new(edwards25519.Point).ScalarBaseMult(a), T)
+		R = new(edwards25519.Point).ScalarBaseMult(r)
+		h = sha512.New()
+	)
+	h.Write(R.Bytes())
+	h.Write(A.Bytes())
+	h.Write(msg)
+	k, err := new(edwards25519.Scalar).SetUniformBytes(h.Sum(nil))
+	require.Nil(t, err)
+	s := new(edwards25519.Scalar).MultiplyAdd(k, a, r)
+	return PublicKeyFromBytes(A.Bytes()), SignatureFromBytes(append(R.Bytes(), s.Bytes()...))
+}
+
+func TestVerifyMatchesBatch(t *testing.T) {
+	msg := []byte("foo")
+	pubKey, sig := torsionSignature(t, msg)
+	assert.True(t, sig.Verify(pubKey, msg))
+
+	batch := newTestBatch(15)
+	batch.Add(pubKey, msg, sig)
+	assert.True(t, batch.Verify())
+	assert.Empty(t, batch.Failed())
+}
+
+func TestVerifySmallOrderPoints(t *testing.T) {
+	var (
+		msg      = []byte("foo")
+		identity = edwards25519.NewIdentityPoint().Bytes()
+		zero     = make([]byte, 32)
+		torsion  = mustDecodeHex(t, "26e8958fc2b227b045c3f489f2ef98f0d5dfac05d3c63339b13802886d53fc05")
+	)
+	verifyBoth := func(pubKey []byte, sig []byte) (bool, bool) {
+		batch := newTestBatch(15)
+		batch.Add(PublicKeyFromBytes(pubKey), msg, SignatureFromBytes(sig))
+		return SignatureFromBytes(sig).Verify(PublicKeyFromBytes(pubKey), msg), batch.Verify()
+	}
+
+	// [0]B = [8]I + [8][k]A holds for every message and small order A
+	for _, pubKey := range [][]byte{identity, torsion} {
+		single, batched := verifyBoth(pubKey, append(append([]byte{}, identity...), zero...))
+		assert.False(t, single)
+		assert.False(t, batched)
+	}
+
+	// a small order R next to a valid one
+	privKey := GeneratePrivateKey()
+	sig := privKey.Sign(msg).Bytes()
+	copy(sig[:32], torsion)
+	single, batched := verifyBoth(privKey.Public().Bytes(), sig)
+	assert.False(t, single)
+	assert.False(t, batched)
+}
+
+func TestDecodeNonCanonicalPoint(t *testing.T) {
+	base := edwards25519.NewGeneratorPoint().Bytes()
+	_, err := decodePoint(base)
+	require.Nil(t, err)
+
+	// the identity encoded with y = p + 1
+	nonCanonical := append([]byte{0xee}, bytes.Repeat([]byte{0xff}, 30)...)
+	_, err = decodePoint(append(nonCanonical, 0x7f))
+	assert.ErrorContains(t, err, "non canonical")
+
+	// the sign bit set for x = 0
+	negativeZero := edwards25519.NewIdentityPoint().Bytes()
+	negativeZero[31] |= 0x80
+	_, err = decodePoint(negativeZero)
+	assert.ErrorContains(t, err, "non canonical")
+}
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	require.Nil(t, err)
+	return b
+}
+
+func BenchmarkBatchVerify(b *testing.B) {
+	batch := newTestBatch(256)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		batch.Verify()
+	}
+}
+
+func BenchmarkVerify(b *testing.B) {
+	batch := newTestBatch(256)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		batch.Failed()
+	}
+}
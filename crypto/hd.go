@@ -0,0 +1,117 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Hierarchical deterministic key derivation for ed25519 as specified by
+// SLIP-0010. Ed25519 only supports hardened derivation, so every index in
+// a path has to be hardened.
+
+const (
+	HardenedOffset   uint32 = 0x80000000
+	ChainCodeLen            = 32
+	MinMasterSeedLen        = 16
+	MaxMasterSeedLen        = 64
+	// CoinType used in wallet paths, SLIP-0044 reserves 1 for all testnets
+	CoinType = 1
+)
+
+var masterKeySalt = []byte("ed25519 seed")
+
+type ExtendedKey struct {
+	seed      []byte
+	chainCode []byte
+}
+
+func NewMasterKey(seed []byte) (*ExtendedKey, error) {
+	if len(seed) < MinMasterSeedLen || len(seed) > MaxMasterSeedLen {
+		return nil, fmt.Errorf("invalid master seed length (%d), must be between %d and %d", len(seed), MinMasterSeedLen, MaxMasterSeedLen)
+	}
+	mac := hmac.New(sha512.New, masterKeySalt)
+	mac.Write(seed)
+	return newExtendedKey(mac.Sum(nil)), nil
+}
+
+func newExtendedKey(sum []byte) *ExtendedKey {
+	return &ExtendedKey{
+		seed:      sum[:SeedLen],
+		chainCode: sum[SeedLen:],
+	}
+}
+
+// Derive returns the child key at the given index, which has to be hardened.
+func (k *ExtendedKey) Derive(index uint32) (*ExtendedKey, error) {
+	if index < HardenedOffset {
+		return nil, fmt.Errorf("ed25519 only supports hardened derivation, index %d is not hardened", index)
+	}
+	data := make([]byte, 0, 1+SeedLen+4)
+	data = append(data, 0x00)
+	data = append(data, k.seed...)
+	data = binary.BigEndian.AppendUint32(data, index)
+
+	mac := hmac.New(sha512.New, k.chainCode)
+	mac.Write(data)
+	return newExtendedKey(mac.Sum(nil)), nil
+}
+
+// DerivePath derives the key at a path like m/44'/0'/1'. Hardened indexes
+// are marked with ', h or H.
+func (k *ExtendedKey) DerivePath(path string) (*ExtendedKey, error) {
+	indexes, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	key := k
+	for _, index := range indexes {
+		if key, err = key.Derive(index); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+func (k *ExtendedKey) PrivateKey() *PrivateKeys {
+	return NewPrivateKeyFromSeed(k.seed)
+}
+
+func (k *ExtendedKey) Seed() []byte {
+	return k.seed
+}
+
+func (k *ExtendedKey) ChainCode() []byte {
+	return k.chainCode
+}
+
+func ParsePath(path string) ([]uint32, error) {
+	segments := strings.Split(path, "/")
+	if segments[0] != "m" {
+		return nil, fmt.Errorf("invalid path %q, must start with m", path)
+	}
+	indexes := make([]uint32, 0, len(segments)-1)
+	for _, segment := range segments[1:] {
+		hardened := strings.HasSuffix(segment, "'") || strings.HasSuffix(segment, "h") || strings.HasSuffix(segment, "H")
+		if hardened {
+			segment = segment[:len(segment)-1]
+		}
+		index, err := strconv.ParseUint(segment, 10, 32)
+		if err != nil || uint32(index) >= HardenedOffset {
+			return nil, fmt.Errorf("invalid path %q, bad index %q", path, segment)
+		}
+		if !hardened {
+			return nil, fmt.Errorf("invalid path %q, index %q is not hardened", path, segment)
+		}
+		indexes = append(indexes, uint32(index)+HardenedOffset)
+	}
+	return indexes, nil
+}
+
+// WalletPath returns the path of the address at index of a wallet account.
+func WalletPath(account uint32, index uint32) string {
+	return fmt.Sprintf("m/44'/%d'/%d'/0'/%d'", CoinType, account, index)
+}
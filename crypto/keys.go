@@ -118,8 +118,10 @@ func (s *Signature) Bytes() []byte {
 	return s.value
 }
 
+// Verify checks the signature with the cofactored equation that batches
+// use as well, see verify.
 func (s *Signature) Verify(pubKey *PublicKeys, msg []byte) bool {
-	return ed25519.Verify(pubKey.key, msg, s.value)
+	return verify(pubKey.key, msg, s.value)
 }
 
 func (a Address) Bytes() []byte {
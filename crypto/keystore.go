@@ -0,0 +1,276 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Keystore files hold a private key seed encrypted with a key derived from
+// a password. The file is versioned JSON so the format can evolve.
+
+const (
+	KeystoreVersion = 1
+
+	KDFScrypt   = "scrypt"
+	KDFArgon2id = "argon2id"
+
+	CipherAES256GCM = "aes-256-gcm"
+
+	keystoreKeyLen  = 32
+	keystoreSaltLen = 32
+
+	// keystore files are not trusted to pick the cost of loading them, the
+	// limits are well above the standard params
+	maxKDFMemory   = 1 << 30
+	maxScryptWork  = 1 << 24
+	maxArgon2Time  = 16
+	maxKDFSaltSize = 1 << 10
+)
+
+type KDFParams struct {
+	Name string `json:"name"`
+	Salt string `json:"salt"`
+
+	// scrypt
+	N int `json:"n,omitempty"`
+	R int `json:"r,omitempty"`
+	P int `json:"p,omitempty"`
+
+	// argon2id
+	Time    uint32 `json:"time,omitempty"`
+	Memory  uint32 `json:"memory,omitempty"`
+	Threads uint8  `json:"threads,omitempty"`
+}
+
+var (
+	StandardScrypt = KDFParams{Name: KDFScrypt, N: 1 << 18, R: 8, P: 1}
+	// LightScrypt uses 4MB of memory, use it where key loading has to be fast
+	LightScrypt      = KDFParams{Name: KDFScrypt, N: 1 << 12, R: 8, P: 6}
+	StandardArgon2id = KDFParams{Name: KDFArgon2id, Time: 3, Memory: 64 * 1024, Threads: 4}
+)
+
+type KeystoreCrypto struct {
+	Cipher     string    `json:"cipher"`
+	Nonce      string    `json:"nonce"`
+	CipherText string    `json:"ciphertext"`
+	KDF        KDFParams `json:"kdf"`
+}
+
+type Keystore struct {
+	Version int            `json:"version"`
+	Address string         `json:"address"`
+	Crypto  KeystoreCrypto `json:"crypto"`
+}
+
+// checkCost rejects the params that are invalid or would take too much
+// memory or time.
+func (p KDFParams) checkCost() error {
+	switch p.Name {
+	case KDFScrypt:
+		// scrypt uses 128*N*r bytes of memory and N*r*p steps
+		if p.N <= 0 || p.R <= 0 || p.P <= 0 {
+			return fmt.Errorf("invalid scrypt parameters")
+		}
+		if p.N > maxKDFMemory/128/p.R || p.P > maxScryptWork/(p.N*p.R) {
+			return fmt.Errorf("scrypt parameters too costly (n=%d r=%d p=%d)", p.N, p.R, p.P)
+		}
+	case KDFArgon2id:
+		if p.Time == 0 || p.Memory == 0 || p.Threads == 0 {
+			return fmt.Errorf("invalid argon2id parameters")
+		}
+		// memory is in KiB
+		if p.Memory > maxKDFMemory/1024 || p.Time > maxArgon2Time {
+			return fmt.Errorf("argon2id parameters too costly (time=%d memory=%d)", p.Time, p.Memory)
+		}
+	default:
+		return fmt.Errorf("unsupported kdf %q", p.Name)
+	}
+	return nil
+}
+
+func (p KDFParams) deriveKey(password string) ([]byte, error) {
+	salt, err := hex.DecodeString(p.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kdf salt: %w", err)
+	}
+	if len(salt) > maxKDFSaltSize {
+		return nil, fmt.Errorf("kdf salt of %d bytes, at most %d", len(salt), maxKDFSaltSize)
+	}
+	if err := p.checkCost(); err != nil {
+		return nil, err
+	}
+	if p.Name == KDFScrypt {
+		return scrypt.Key([]byte(password), salt, p.N, p.R, p.P, keystoreKeyLen)
+	}
+	return argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, keystoreKeyLen), nil
+}
+
+func newKeystoreCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptKey encrypts the seed of the private key with a fresh salt and nonce.
+func EncryptKey(privKey *PrivateKeys, password string, params KDFParams) (*Keystore, error) {
+	salt := make([]byte, keystoreSaltLen)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	params.Salt = hex.EncodeToString(salt)
+
+	key, err := params.deriveKey(password)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newKeystoreCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	ks := &Keystore{
+		Version: KeystoreVersion,
+		Address: privKey.Public().Address().String(),
+		Crypto: KeystoreCrypto{
+			Cipher: CipherAES256GCM,
+			Nonce:  hex.EncodeToString(nonce),
+			KDF:    params,
+		},
+	}
+	cipherText := aead.Seal(nil, nonce, privKey.key.Seed(), ks.additionalData())
+	ks.Crypto.CipherText = hex.EncodeToString(cipherText)
+	return ks, nil
+}
+
+// Decrypt returns the private key of the keystore. A wrong password and a
+// tampered file both fail authentication.
+func (ks *Keystore) Decrypt(password string) (*PrivateKeys, error) {
+	if ks.Version != KeystoreVersion {
+		return nil, fmt.Errorf("unsupported keystore version %d", ks.Version)
+	}
+	if ks.Crypto.Cipher != CipherAES256GCM {
+		return nil, fmt.Errorf("unsupported keystore cipher %q", ks.Crypto.Cipher)
+	}
+	nonce, err := hex.DecodeString(ks.Crypto.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore nonce: %w", err)
+	}
+	cipherText, err := hex.DecodeString(ks.Crypto.CipherText)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore ciphertext: %w", err)
+	}
+
+	key, err := ks.Crypto.KDF.deriveKey(password)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newKeystoreCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid keystore nonce length (%d)", len(nonce))
+	}
+	seed, err := aead.Open(nil, nonce, cipherText, ks.additionalData())
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt keystore: wrong password or corrupted file")
+	}
+	if len(seed) != SeedLen {
+		return nil, fmt.Errorf("invalid keystore seed length (%d)", len(seed))
+	}
+
+	privKey := NewPrivateKeyFromSeed(seed)
+	if privKey.Public().Address().String() != ks.Address {
+		return nil, fmt.Errorf("keystore address mismatch")
+	}
+	return privKey, nil
+}
+
+// the version and address are authenticated together with the seed
+func (ks *Keystore) additionalData() []byte {
+	return []byte(fmt.Sprintf("%d:%s", ks.Version, ks.Address))
+}
+
+func SaveKeystore(path string, privKey *PrivateKeys, password string, params KDFParams) error {
+	ks, err := EncryptKey(privKey, password, params)
+	if err != nil {
+		return err
+	}
+	return writeKeystore(path, ks)
+}
+
+func LoadKeystore(path string, password string) (*PrivateKeys, error) {
+	ks, err := ReadKeystore(path)
+	if err != nil {
+		return nil, err
+	}
+	return ks.Decrypt(password)
+}
+
+func ReadKeystore(path string) (*Keystore, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ks := &Keystore{}
+	if err := json.Unmarshal(b, ks); err != nil {
+		return nil, fmt.Errorf("invalid keystore file %s: %w", path, err)
+	}
+	return ks, nil
+}
+
+// ChangeKeystorePassword re-encrypts the keystore at path, keeping its kdf.
+func ChangeKeystorePassword(path string, oldPassword string, newPassword string) error {
+	ks, err := ReadKeystore(path)
+	if err != nil {
+		return err
+	}
+	privKey, err := ks.Decrypt(oldPassword)
+	if err != nil {
+		return err
+	}
+	return SaveKeystore(path, privKey, newPassword, ks.Crypto.KDF)
+}
+
+// writeKeystore replaces the file atomically, so a crash never leaves a
+// half written key behind.
+func writeKeystore(path string, ks *Keystore) error {
+	b, err := json.MarshalIndent(ks, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".keystore-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
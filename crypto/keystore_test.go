@@ -0,0 +1,114 @@
+package crypto
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testArgon2id = KDFParams{Name: KDFArgon2id, Time: 1, Memory: 1024, Threads: 1}
+
+func TestKeystoreEncryptDecrypt(t *testing.T) {
+	for _, params := range []KDFParams{LightScrypt, testArgon2id} {
+		privKey := GeneratePrivateKey()
+		ks, err := EncryptKey(privKey, "hunter2", params)
+		require.Nil(t, err)
+		assert.Equal(t, KeystoreVersion, ks.Version)
+		assert.Equal(t, privKey.Public().Address().String(), ks.Address)
+
+		decrypted, err := ks.Decrypt("hunter2")
+		require.Nil(t, err)
+		assert.Equal(t, privKey.Bytes(), decrypted.Bytes())
+
+		_, err = ks.Decrypt("hunter3")
+		assert.NotNil(t, err)
+	}
+}
+
+func TestKeystoreTampered(t *testing.T) {
+	privKey := GeneratePrivateKey()
+	ks, err := EncryptKey(privKey, "hunter2", LightScrypt)
+	require.Nil(t, err)
+
+	tampered := *ks
+	tampered.Address = GeneratePrivateKey().Public().Address().String()
+	_, err = tampered.Decrypt("hunter2")
+	assert.NotNil(t, err)
+
+	tampered = *ks
+	tampered.Version = 2
+	_, err = tampered.Decrypt("hunter2")
+	assert.NotNil(t, err)
+
+	tampered = *ks
+	tampered.Crypto.KDF.Name = "pbkdf2"
+	_, err = tampered.Decrypt("hunter2")
+	assert.NotNil(t, err)
+}
+
+func TestKeystoreCostlyParams(t *testing.T) {
+	ks, err := EncryptKey(GeneratePrivateKey(), "hunter2", testArgon2id)
+	require.Nil(t, err)
+
+	// a file asking for terabytes of memory or years of work fails at once
+	tests := map[string]func(p *KDFParams){
+		"argon2id memory": func(p *KDFParams) { p.Memory = 1 << 31 },
+		"argon2id time":   func(p *KDFParams) { p.Time = 1 << 31 },
+		"scrypt n":        func(p *KDFParams) { *p = KDFParams{Name: KDFScrypt, Salt: p.Salt, N: 1 << 40, R: 8, P: 1} },
+		"scrypt r":        func(p *KDFParams) { *p = KDFParams{Name: KDFScrypt, Salt: p.Salt, N: 2, R: 1 << 40, P: 1} },
+		"scrypt p":        func(p *KDFParams) { *p = KDFParams{Name: KDFScrypt, Salt: p.Salt, N: 1 << 18, R: 8, P: 1 << 20} },
+		"scrypt negative": func(p *KDFParams) { *p = KDFParams{Name: KDFScrypt, Salt: p.Salt, N: -1, R: -8, P: 1} },
+	}
+	for name, change := range tests {
+		t.Run(name, func(t *testing.T) {
+			costly := *ks
+			change(&costly.Crypto.KDF)
+			_, err := costly.Decrypt("hunter2")
+			assert.NotNil(t, err)
+		})
+	}
+
+	// the standard params stay within the limits
+	for _, params := range []KDFParams{StandardScrypt, LightScrypt, StandardArgon2id} {
+		assert.Nil(t, params.checkCost())
+	}
+}
+
+func TestKeystoreFile(t *testing.T) {
+	var (
+		path    = filepath.Join(t.TempDir(), "validator.json")
+		privKey = GeneratePrivateKey()
+	)
+	require.Nil(t, SaveKeystore(path, privKey, "hunter2", LightScrypt))
+
+	info, err := os.Stat(path)
+	require.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	b, err := os.ReadFile(path)
+	require.Nil(t, err)
+	ks := &Keystore{}
+	require.Nil(t, json.Unmarshal(b, ks))
+	assert.Equal(t, KDFScrypt, ks.Crypto.KDF.Name)
+	assert.Equal(t, CipherAES256GCM, ks.Crypto.Cipher)
+
+	loaded, err := LoadKeystore(path, "hunter2")
+	require.Nil(t, err)
+	assert.Equal(t, privKey.Bytes(), loaded.Bytes())
+
+	require.NotNil(t, ChangeKeystorePassword(path, "wrong", "correct horse"))
+	require.Nil(t, ChangeKeystorePassword(path, "hunter2", "correct horse"))
+
+	_, err = LoadKeystore(path, "hunter2")
+	assert.NotNil(t, err)
+	loaded, err = LoadKeystore(path, "correct horse")
+	require.Nil(t, err)
+	assert.Equal(t, privKey.Bytes(), loaded.Bytes())
+
+	_, err = LoadKeystore(filepath.Join(t.TempDir(), "missing.json"), "hunter2")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,173 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	_ "embed"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Mnemonic seed phrases as specified by BIP-39. Keys of a new mnemonic are
+// derived from its BIP-39 seed, with an optional passphrase. An existing
+// key can be written down too: a 24 word mnemonic encodes exactly SeedLen
+// bytes of entropy, the seed of the key itself, without a passphrase.
+
+const (
+	MnemonicSeedLen    = 64
+	mnemonicIterations = 2048
+	bitsPerWord        = 11
+)
+
+//go:embed wordlist/english.txt
+var englishWordList string
+
+var (
+	wordList  = strings.Fields(englishWordList)
+	wordIndex = func() map[string]int {
+		index := make(map[string]int, len(wordList))
+		for i, word := range wordList {
+			index[word] = i
+		}
+		return index
+	}()
+)
+
+// NewMnemonic returns a 24 word mnemonic of SeedLen random bytes.
+func NewMnemonic() string {
+	entropy := make([]byte, SeedLen)
+	_, err := io.ReadFull(rand.Reader, entropy)
+	if err != nil {
+		panic(err)
+	}
+	mnemonic, err := EntropyToMnemonic(entropy)
+	if err != nil {
+		panic(err)
+	}
+	return mnemonic
+}
+
+// EntropyToMnemonic encodes 16, 20, 24, 28 or 32 bytes of entropy as 12 to 24 words.
+func EntropyToMnemonic(entropy []byte) (string, error) {
+	if len(entropy) < 16 || len(entropy) > 32 || len(entropy)%4 != 0 {
+		return "", fmt.Errorf("invalid entropy length (%d), must be a multiple of 4 between 16 and 32", len(entropy))
+	}
+	var (
+		checksumBits = len(entropy) / 4
+		nWords       = (len(entropy)*8 + checksumBits) / bitsPerWord
+		checksum     = sha256.Sum256(entropy)
+		bits         = append(append([]byte{}, entropy...), checksum[0])
+		words        = make([]string, nWords)
+	)
+	for i := 0; i < nWords; i++ {
+		index := 0
+		for b := i * bitsPerWord; b < (i+1)*bitsPerWord; b++ {
+			index = index<<1 | bit(bits, b)
+		}
+		words[i] = wordList[index]
+	}
+	return strings.Join(words, " "), nil
+}
+
+// MnemonicToEntropy decodes a mnemonic back into its entropy and verifies the checksum.
+func MnemonicToEntropy(mnemonic string) ([]byte, error) {
+	words := strings.Fields(norm.NFKD.String(mnemonic))
+	if len(words) < 12 || len(words) > 24 || len(words)%3 != 0 {
+		return nil, fmt.Errorf("invalid mnemonic length (%d words)", len(words))
+	}
+	var (
+		nBits        = len(words) * bitsPerWord
+		checksumBits = nBits / 33
+		entropy      = make([]byte, (nBits-checksumBits)/8)
+		checksum     = 0
+	)
+	for i, word := range words {
+		index, ok := wordIndex[word]
+		if !ok {
+			return nil, fmt.Errorf("invalid mnemonic word %q", word)
+		}
+		for j := bitsPerWord - 1; j >= 0; j-- {
+			b := i*bitsPerWord + (bitsPerWord - 1 - j)
+			v := (index >> j) & 1
+			if b < len(entropy)*8 {
+				entropy[b/8] |= byte(v << (7 - b%8))
+			} else {
+				checksum = checksum<<1 | v
+			}
+		}
+	}
+	hash := sha256.Sum256(entropy)
+	if int(hash[0]>>(8-checksumBits)) != checksum {
+		return nil, fmt.Errorf("invalid mnemonic checksum")
+	}
+	return entropy, nil
+}
+
+func ValidateMnemonic(mnemonic string) bool {
+	_, err := MnemonicToEntropy(mnemonic)
+	return err == nil
+}
+
+// MnemonicToSeed returns the 64 byte BIP-39 seed of the mnemonic, which can
+// be used as the master seed for hierarchical derivation.
+func MnemonicToSeed(mnemonic string, passphrase string) ([]byte, error) {
+	if !ValidateMnemonic(mnemonic) {
+		return nil, fmt.Errorf("invalid mnemonic")
+	}
+	var (
+		password = []byte(strings.Join(strings.Fields(norm.NFKD.String(mnemonic)), " "))
+		salt     = []byte("mnemonic" + norm.NFKD.String(passphrase))
+	)
+	return pbkdf2.Key(password, salt, mnemonicIterations, MnemonicSeedLen, sha512.New), nil
+}
+
+// NewMasterKeyFromMnemonic returns the root of the key hierarchy of a wallet.
+func NewMasterKeyFromMnemonic(mnemonic string, passphrase string) (*ExtendedKey, error) {
+	seed, err := MnemonicToSeed(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return NewMasterKey(seed)
+}
+
+// NewPrivateKeyFromMnemonic returns the key of the first SeedLen bytes of
+// the BIP-39 seed of the mnemonic and passphrase.
+func NewPrivateKeyFromMnemonic(mnemonic string, passphrase string) (*PrivateKeys, error) {
+	seed, err := MnemonicToSeed(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return NewPrivateKeyFromSeed(seed[:SeedLen]), nil
+}
+
+// NewPrivateKeyFromSeedMnemonic restores a key whose seed was exported with
+// Mnemonic.
+func NewPrivateKeyFromSeedMnemonic(mnemonic string) (*PrivateKeys, error) {
+	entropy, err := MnemonicToEntropy(mnemonic)
+	if err != nil {
+		return nil, err
+	}
+	if len(entropy) != SeedLen {
+		return nil, fmt.Errorf("invalid mnemonic length, a private key needs 24 words")
+	}
+	return NewPrivateKeyFromSeed(entropy), nil
+}
+
+// Mnemonic returns the 24 word backup phrase of the key's seed, see
+// NewPrivateKeyFromSeedMnemonic.
+func (p *PrivateKeys) Mnemonic() string {
+	mnemonic, err := EntropyToMnemonic(p.key.Seed())
+	if err != nil {
+		panic(err)
+	}
+	return mnemonic
+}
+
+func bit(b []byte, i int) int {
+	return int(b[i/8]>>(7-i%8)) & 1
+}
@@ -0,0 +1,92 @@
+package crypto
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+const certificateValidity = time.Hour * 24 * 365 * 10
+
+// NewCertificate creates a self signed TLS certificate for the key. Nodes
+// authenticate each other by the certificate key, not by a CA, so the
+// certificate only carries the public key.
+func NewCertificate(privKey *PrivateKeys) (tls.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName: hex.EncodeToString(privKey.Public().Bytes()),
+		},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(certificateValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, ed25519.PublicKey(privKey.Public().Bytes()), privKey.key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  privKey.key,
+	}, nil
+}
+
+// VerifyCertificate checks that the raw certificate is a valid self signed
+// ed25519 certificate and returns its key.
+func VerifyCertificate(raw []byte) (*PublicKeys, error) {
+	cert, err := x509.ParseCertificate(raw)
+	if err != nil {
+		return nil, err
+	}
+	pubKey, ok := cert.PublicKey.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("certificate key is not ed25519")
+	}
+	if err := cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return nil, fmt.Errorf("certificate expired or not yet valid")
+	}
+	return PublicKeyFromBytes(pubKey), nil
+}
+
+// NewTLSConfig returns a TLS config that presents the certificate of the
+// key and requires the remote side to present a valid self signed ed25519
+// certificate too. Binding the certificate key to an identity is up to the
+// caller.
+func NewTLSConfig(privKey *PrivateKeys) (*tls.Config, error) {
+	cert, err := NewCertificate(privKey)
+	if err != nil {
+		return nil, err
+	}
+	verify := func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return fmt.Errorf("no certificate presented")
+		}
+		_, err := VerifyCertificate(rawCerts[0])
+		return err
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS13,
+		ClientAuth:   tls.RequireAnyClientCert,
+		// there is no CA, the certificate is checked by VerifyPeerCertificate
+		InsecureSkipVerify:    true,
+		VerifyPeerCertificate: verify,
+	}, nil
+}
@@ -0,0 +1,56 @@
+package crypto
+
+import (
+	"crypto/tls"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCertificate(t *testing.T) {
+	privKey := GeneratePrivateKey()
+	cert, err := NewCertificate(privKey)
+	require.Nil(t, err)
+
+	pubKey, err := VerifyCertificate(cert.Certificate[0])
+	require.Nil(t, err)
+	assert.Equal(t, privKey.Public().Bytes(), pubKey.Bytes())
+
+	// a certificate with a broken signature
+	raw := append([]byte{}, cert.Certificate[0]...)
+	raw[len(raw)-1] ^= 0xff
+	_, err = VerifyCertificate(raw)
+	assert.NotNil(t, err)
+}
+
+func TestTLSConfigMutualAuth(t *testing.T) {
+	var (
+		serverKey = GeneratePrivateKey()
+		clientKey = GeneratePrivateKey()
+	)
+	serverConfig, err := NewTLSConfig(serverKey)
+	require.Nil(t, err)
+	clientConfig, err := NewTLSConfig(clientKey)
+	require.Nil(t, err)
+
+	clientConn, serverConn := net.Pipe()
+	var (
+		server = tls.Server(serverConn, serverConfig)
+		client = tls.Client(clientConn, clientConfig)
+		errc   = make(chan error, 1)
+	)
+	go func() {
+		errc <- server.Handshake()
+	}()
+	require.Nil(t, client.Handshake())
+	require.Nil(t, <-errc)
+
+	remote, err := VerifyCertificate(client.ConnectionState().PeerCertificates[0].Raw)
+	require.Nil(t, err)
+	assert.Equal(t, serverKey.Public().Bytes(), remote.Bytes())
+	remote, err = VerifyCertificate(server.ConnectionState().PeerCertificates[0].Raw)
+	require.Nil(t, err)
+	assert.Equal(t, clientKey.Public().Bytes(), remote.Bytes())
+}
@@ -3,6 +3,9 @@ package main
 import (
 	"context"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/64bitAryan/blocker/crypto"
@@ -22,29 +25,55 @@ ____steps to create grpc server with protobuf____
 */
 
 func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	makeNode(":3000", []string{}, true)
+	nodes := []*node.Node{
+		makeNode(ctx, ":3000", []string{}, true),
+	}
+	time.Sleep(time.Second)
 	time.Sleep(time.Second)
-    time.Sleep(time.Second)
-	makeNode(":4000", []string{":3000"}, false)
+	nodes = append(nodes, makeNode(ctx, ":4000", []string{":3000"}, false))
 	time.Sleep(time.Second)
-	makeNode(":5000", []string{":4000"}, false)
-	for {
+	nodes = append(nodes, makeNode(ctx, ":5000", []string{":4000"}, false))
+	for ctx.Err() == nil {
 		time.Sleep(time.Millisecond * 800)
 		makeTaransaction()
 	}
+	for _, n := range nodes {
+		n.Stop()
+	}
 }
 
-func makeNode(listenAddr string, bootstrapNodes []string, isValidator bool) *node.Node {
+func makeNode(ctx context.Context, listenAddr string, bootstrapNodes []string, isValidator bool) *node.Node {
 	cfg := node.ServerConfig{
 		Version:    "blocker-1",
 		ListenAddr: listenAddr,
 	}
+	if path := os.Getenv("BLOCKER_GENESIS"); len(path) > 0 {
+		genesis, err := node.LoadGenesis(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		cfg.Genesis = genesis
+	}
+	if len(os.Getenv("BLOCKER_INSTAMINE")) > 0 {
+		cfg.Production.Mode = node.ProduceInstamine
+	}
 	if isValidator {
-		cfg.PrivateKey = crypto.GeneratePrivateKey()
+		if keystore := os.Getenv("BLOCKER_KEYSTORE"); len(keystore) > 0 {
+			cfg.KeystoreFile = keystore
+			cfg.KeystorePassword = os.Getenv("BLOCKER_KEYSTORE_PASSWORD")
+		} else {
+			cfg.PrivateKey = crypto.GeneratePrivateKey()
+		}
 	}
 	n := node.NewNode(cfg)
-	go n.Start(listenAddr, bootstrapNodes)
+	go func() {
+		if err := n.Start(ctx, listenAddr, bootstrapNodes); err != nil {
+			log.Fatal(err)
+		}
+	}()
 	return n
 
 }
@@ -55,6 +84,7 @@ func makeTaransaction() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer client.Close()
 
 	//
 	c := proto.NewNodeClient(client)
@@ -77,8 +107,9 @@ func makeTaransaction() {
 			},
 		},
 	}
+	// the inputs are random, so the node rejects the transaction
 	_, err = c.HandleTransaction(context.TODO(), tx)
 	if err != nil {
-		log.Fatal(err)
+		log.Println(err)
 	}
 }
@@ -0,0 +1,261 @@
+package node
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// addresses that failed this often without ever being seen are forgotten
+	maxAddrFailures = 10
+	// most addresses answered to or accepted from a single GetAddr
+	maxAddrsPerMessage = 100
+	maxKnownAddrs      = 5000
+)
+
+type KnownAddress struct {
+	Addr string `json:"addr"`
+	// Source is the node that told us about the address
+	Source      string    `json:"source"`
+	LastSeen    time.Time `json:"lastSeen"`
+	LastAttempt time.Time `json:"lastAttempt"`
+	Failures    int       `json:"failures"`
+}
+
+// retryAfter backs off exponentially with the number of failed attempts.
+func (ka *KnownAddress) retryAfter() time.Time {
+	if ka.Failures == 0 {
+		return ka.LastAttempt
+	}
+	delay := min(minBackoff<<min(ka.Failures-1, 16), maxBackoff)
+	return ka.LastAttempt.Add(delay)
+}
+
+// AddrBook remembers the addresses of nodes in the network, together with
+// how reliable they were, across restarts.
+type AddrBook struct {
+	lock  sync.RWMutex
+	path  string
+	addrs map[string]*KnownAddress
+}
+
+// NewAddrBook loads the address book stored at path, an empty path keeps
+// the addresses in memory only.
+func NewAddrBook(path string) (*AddrBook, error) {
+	book := &AddrBook{
+		path:  path,
+		addrs: make(map[string]*KnownAddress),
+	}
+	if len(path) == 0 {
+		return book, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return book, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	addrs := []*KnownAddress{}
+	if err := json.Unmarshal(b, &addrs); err != nil {
+		return nil, err
+	}
+	for _, ka := range addrs {
+		book.addrs[ka.Addr] = ka
+	}
+	return book, nil
+}
+
+// Add reports whether addr was not known before.
+func (book *AddrBook) Add(addr string, source string) bool {
+	book.lock.Lock()
+	defer book.lock.Unlock()
+	if _, ok := book.addrs[addr]; ok || len(book.addrs) >= maxKnownAddrs {
+		return false
+	}
+	book.addrs[addr] = &KnownAddress{
+		Addr:   addr,
+		Source: source,
+	}
+	return true
+}
+
+func (book *AddrBook) MarkAttempt(addr string) {
+	book.lock.Lock()
+	defer book.lock.Unlock()
+	if ka, ok := book.addrs[addr]; ok {
+		ka.LastAttempt = time.Now()
+	}
+}
+
+func (book *AddrBook) MarkGood(addr string) {
+	book.lock.Lock()
+	defer book.lock.Unlock()
+	if ka, ok := book.addrs[addr]; ok {
+		ka.LastSeen = time.Now()
+		ka.Failures = 0
+	}
+}
+
+func (book *AddrBook) MarkFailed(addr string) {
+	book.lock.Lock()
+	defer book.lock.Unlock()
+	ka, ok := book.addrs[addr]
+	if !ok {
+		return
+	}
+	ka.Failures++
+	if ka.Failures >= maxAddrFailures && ka.LastSeen.IsZero() {
+		delete(book.addrs, addr)
+	}
+}
+
+func (book *AddrBook) Get(addr string) (KnownAddress, bool) {
+	book.lock.RLock()
+	defer book.lock.RUnlock()
+	ka, ok := book.addrs[addr]
+	if !ok {
+		return KnownAddress{}, false
+	}
+	return *ka, true
+}
+
+func (book *AddrBook) Len() int {
+	book.lock.RLock()
+	defer book.lock.RUnlock()
+	return len(book.addrs)
+}
+
+// Sample returns up to max random addresses that were seen at least once.
+func (book *AddrBook) Sample(max int) []string {
+	book.lock.RLock()
+	defer book.lock.RUnlock()
+	addrs := []string{}
+	for addr, ka := range book.addrs {
+		if !ka.LastSeen.IsZero() {
+			addrs = append(addrs, addr)
+		}
+	}
+	rand.Shuffle(len(addrs), func(i, j int) {
+		addrs[i], addrs[j] = addrs[j], addrs[i]
+	})
+	return addrs[:min(max, len(addrs))]
+}
+
+// Pick selects up to n addresses to dial. Addresses are taken round robin
+// from the nodes that told us about them, so a single source can't fill
+// all outbound slots, and addresses sharing a network group with an
+// earlier pick are only used when nothing else is left.
+func (book *AddrBook) Pick(n int, now time.Time, skip func(addr string) bool) []string {
+	book.lock.RLock()
+	defer book.lock.RUnlock()
+
+	sources := make(map[string][]*KnownAddress)
+	for addr, ka := range book.addrs {
+		if skip(addr) || now.Before(ka.retryAfter()) {
+			continue
+		}
+		sources[ka.Source] = append(sources[ka.Source], ka)
+	}
+	keys := make([]string, 0, len(sources))
+	for source, addrs := range sources {
+		keys = append(keys, source)
+		// the most reliable addresses of every source first
+		sort.Slice(addrs, func(i, j int) bool {
+			if addrs[i].Failures != addrs[j].Failures {
+				return addrs[i].Failures < addrs[j].Failures
+			}
+			return addrs[i].LastSeen.After(addrs[j].LastSeen)
+		})
+	}
+	rand.Shuffle(len(keys), func(i, j int) {
+		keys[i], keys[j] = keys[j], keys[i]
+	})
+
+	var (
+		picked = []string{}
+		groups = make(map[string]bool)
+		used   = make(map[string]bool)
+	)
+	for _, diverse := range []bool{true, false} {
+		for progress := true; progress && len(picked) < n; {
+			progress = false
+			for _, source := range keys {
+				if len(picked) == n {
+					break
+				}
+				for _, ka := range sources[source] {
+					group := netGroup(ka.Addr)
+					if used[ka.Addr] || (diverse && groups[group]) {
+						continue
+					}
+					used[ka.Addr] = true
+					groups[group] = true
+					picked = append(picked, ka.Addr)
+					progress = true
+					break
+				}
+			}
+		}
+	}
+	return picked
+}
+
+// Save writes the address book to its path, replacing the file atomically.
+func (book *AddrBook) Save() error {
+	if len(book.path) == 0 {
+		return nil
+	}
+	book.lock.RLock()
+	addrs := make([]*KnownAddress, 0, len(book.addrs))
+	for _, ka := range book.addrs {
+		addrs = append(addrs, ka)
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return addrs[i].Addr < addrs[j].Addr
+	})
+	b, err := json.MarshalIndent(addrs, "", "  ")
+	book.lock.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(book.path), ".addrbook-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), book.path)
+}
+
+// netGroup returns the /16 of IPv4 and /32 of IPv6 addresses, hostnames
+// are their own group.
+func netGroup(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return host
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.Mask(net.CIDRMask(16, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(32, 128)).String()
+}
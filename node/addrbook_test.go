@@ -0,0 +1,121 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func noSkip(string) bool { return false }
+
+func TestAddrBookPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "addrbook.json")
+	book, err := NewAddrBook(path)
+	require.Nil(t, err)
+
+	assert.True(t, book.Add("10.0.0.1:3000", "bootstrap"))
+	assert.False(t, book.Add("10.0.0.1:3000", "10.1.0.1:3000"))
+	assert.True(t, book.Add("10.1.0.1:3000", "10.0.0.1:3000"))
+	book.MarkGood("10.0.0.1:3000")
+	book.MarkFailed("10.1.0.1:3000")
+	require.Nil(t, book.Save())
+
+	loaded, err := NewAddrBook(path)
+	require.Nil(t, err)
+	assert.Equal(t, 2, loaded.Len())
+
+	ka, ok := loaded.Get("10.0.0.1:3000")
+	require.True(t, ok)
+	assert.Equal(t, "bootstrap", ka.Source)
+	assert.False(t, ka.LastSeen.IsZero())
+
+	ka, ok = loaded.Get("10.1.0.1:3000")
+	require.True(t, ok)
+	assert.Equal(t, 1, ka.Failures)
+
+	assert.Equal(t, []string{"10.0.0.1:3000"}, loaded.Sample(maxAddrsPerMessage))
+}
+
+func TestAddrBookPickDiversity(t *testing.T) {
+	book, _ := NewAddrBook("")
+	// a single source floods the book with addresses of one /16
+	for i := 0; i < 50; i++ {
+		book.Add(fmt.Sprintf("10.0.0.%d:3000", i), "10.0.0.1:3000")
+	}
+	book.Add("10.1.0.1:3000", "bootstrap")
+	book.Add("10.2.0.1:3000", "10.1.0.1:3000")
+
+	picked := book.Pick(3, time.Now(), noSkip)
+	groups := make(map[string]bool)
+	for _, addr := range picked {
+		groups[netGroup(addr)] = true
+	}
+	assert.Len(t, picked, 3)
+	assert.Len(t, groups, 3)
+
+	// groups are only reused once there is nothing else left
+	picked = book.Pick(10, time.Now(), noSkip)
+	assert.Len(t, picked, 10)
+
+	picked = book.Pick(10, time.Now(), func(addr string) bool {
+		return netGroup(addr) == "10.0.0.0"
+	})
+	assert.ElementsMatch(t, []string{"10.1.0.1:3000", "10.2.0.1:3000"}, picked)
+}
+
+func TestAddrBookBackoff(t *testing.T) {
+	book, _ := NewAddrBook("")
+	addr := "10.0.0.1:3000"
+	book.Add(addr, "bootstrap")
+
+	book.MarkAttempt(addr)
+	book.MarkFailed(addr)
+	now := time.Now()
+	assert.Empty(t, book.Pick(1, now, noSkip))
+	assert.Equal(t, []string{addr}, book.Pick(1, now.Add(minBackoff), noSkip))
+
+	book.MarkAttempt(addr)
+	book.MarkFailed(addr)
+	assert.Empty(t, book.Pick(1, time.Now().Add(minBackoff), noSkip))
+	assert.Equal(t, []string{addr}, book.Pick(1, time.Now().Add(2*minBackoff), noSkip))
+
+	book.MarkGood(addr)
+	assert.Equal(t, []string{addr}, book.Pick(1, time.Now(), noSkip))
+}
+
+func TestAddrBookForgetsFailing(t *testing.T) {
+	book, _ := NewAddrBook("")
+	book.Add("10.0.0.1:3000", "bootstrap")
+	book.Add("10.1.0.1:3000", "bootstrap")
+	book.MarkGood("10.1.0.1:3000")
+
+	for i := 0; i < maxAddrFailures; i++ {
+		book.MarkFailed("10.0.0.1:3000")
+		book.MarkFailed("10.1.0.1:3000")
+	}
+	// addresses that were seen before are kept
+	_, ok := book.Get("10.0.0.1:3000")
+	assert.False(t, ok)
+	_, ok = book.Get("10.1.0.1:3000")
+	assert.True(t, ok)
+}
+
+func TestNetGroup(t *testing.T) {
+	assert.Equal(t, "192.168.0.0", netGroup("192.168.4.5:3000"))
+	assert.Equal(t, netGroup("192.168.4.5:3000"), netGroup("192.168.200.1:4000"))
+	assert.NotEqual(t, netGroup("192.168.4.5:3000"), netGroup("192.169.4.5:3000"))
+	assert.Equal(t, "localhost", netGroup("localhost:3000"))
+}
+
+func TestNodeInboundLimit(t *testing.T) {
+	n := NewNode(ServerConfig{MaxInbound: 1})
+	addFakePeer(n, "10.0.0.1:3000", &fakeNodeClient{alive: true})
+	remote := NewNode(ServerConfig{ListenAddr: "10.2.0.1:3000"})
+	_, err := n.Handshake(context.Background(), signedVersionFor(t, remote, n))
+	assert.ErrorContains(t, err, "too many inbound peers")
+}
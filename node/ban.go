@@ -0,0 +1,260 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/64bitAryan/blocker/proto"
+	grpcpeer "google.golang.org/grpc/peer"
+)
+
+const (
+	// peers reaching this score are banned
+	banThreshold   = 100
+	banDuration    = time.Hour * 24
+	maxBanDuration = time.Hour * 24 * 365
+	// a score drops by one every scoreDecayInterval of good behaviour
+	scoreDecayInterval = time.Second * 10
+
+	penaltyInvalidTx       = 20
+	penaltyInvalidBlock    = 100
+	penaltySpam            = 10
+	penaltyRateLimit       = 1
+	penaltyInvalidVote     = 20
+	penaltyInvalidEvidence = 20
+)
+
+type Ban struct {
+	Addr   string
+	Reason string
+	Until  time.Time
+}
+
+type score struct {
+	value   int
+	updated time.Time
+}
+
+// BanList keeps the misbehaviour scores of peers and clients and the
+// addresses that are banned because of them. Peers are tracked by their
+// listen address, clients by their host.
+type BanList struct {
+	lock   sync.Mutex
+	scores map[string]*score
+	bans   map[string]Ban
+}
+
+func NewBanList() *BanList {
+	return &BanList{
+		scores: make(map[string]*score),
+		bans:   make(map[string]Ban),
+	}
+}
+
+// Penalize adds penalty to the score of addr and reports whether addr got
+// banned for it.
+func (b *BanList) Penalize(addr string, penalty int, reason string, now time.Time) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	s, ok := b.scores[addr]
+	if !ok {
+		s = &score{updated: now}
+		b.scores[addr] = s
+	}
+	decay := int(now.Sub(s.updated) / scoreDecayInterval)
+	s.value = max(s.value-decay, 0) + penalty
+	s.updated = now
+	if s.value < banThreshold {
+		return false
+	}
+	delete(b.scores, addr)
+	b.bans[addr] = Ban{
+		Addr:   addr,
+		Reason: reason,
+		Until:  now.Add(banDuration),
+	}
+	return true
+}
+
+func (b *BanList) Score(addr string, now time.Time) int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	s, ok := b.scores[addr]
+	if !ok {
+		return 0
+	}
+	return max(s.value-int(now.Sub(s.updated)/scoreDecayInterval), 0)
+}
+
+func (b *BanList) Ban(addr string, until time.Time, reason string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	delete(b.scores, addr)
+	b.bans[addr] = Ban{
+		Addr:   addr,
+		Reason: reason,
+		Until:  until,
+	}
+}
+
+// Unban reports whether addr was banned.
+func (b *BanList) Unban(addr string) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	_, ok := b.bans[addr]
+	delete(b.bans, addr)
+	return ok
+}
+
+func (b *BanList) IsBanned(addr string, now time.Time) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	ban, ok := b.bans[addr]
+	if ok && now.After(ban.Until) {
+		delete(b.bans, addr)
+		return false
+	}
+	return ok
+}
+
+// List returns the bans that did not expire yet, sorted by address.
+func (b *BanList) List(now time.Time) []Ban {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	bans := []Ban{}
+	for addr, ban := range b.bans {
+		if now.After(ban.Until) {
+			delete(b.bans, addr)
+			continue
+		}
+		bans = append(bans, ban)
+	}
+	sort.Slice(bans, func(i, j int) bool {
+		return bans[i].Addr < bans[j].Addr
+	})
+	return bans
+}
+
+// misbehaving penalizes the peer listening on addr and disconnects it once
+// it is banned.
+func (n *Node) misbehaving(addr string, penalty int, reason string) {
+	n.logger.Debugw("peer misbehaving", "we", n.ListenAddr, "remote", addr, "penalty", penalty, "reason", reason)
+	if !n.bans.Penalize(addr, penalty, reason, time.Now()) {
+		return
+	}
+	n.logger.Infow("banning peer", "we", n.ListenAddr, "remote", addr, "reason", reason)
+	n.deletePeer(addr)
+}
+
+// clientMisbehaving penalizes the host of a client connection. Clients on
+// the same machine are only spared with TrustLoopback.
+func (n *Node) clientMisbehaving(ctx context.Context, penalty int, reason string) {
+	host := remoteHost(ctx)
+	if len(host) == 0 || (n.TrustLoopback && isLoopback(host)) {
+		return
+	}
+	if n.bans.Penalize(host, penalty, reason, time.Now()) {
+		n.logger.Infow("banning client", "we", n.ListenAddr, "remote", host, "reason", reason)
+	}
+}
+
+// senderMisbehaving penalizes the sender of a message: the peer p when the
+// connection proves it sent the message, the host of the connection else.
+// The listen address a message carries is never trusted for it.
+func (n *Node) senderMisbehaving(ctx context.Context, p *peer, penalty int, reason string) {
+	if p != nil && fromPeer(ctx, p) {
+		n.misbehaving(p.version.ListenAddr, penalty, reason)
+		return
+	}
+	n.clientMisbehaving(ctx, penalty, reason)
+}
+
+// isBanned checks the listen address of a peer as well as the host of the
+// connection it came from.
+func (n *Node) isBanned(ctx context.Context, addr string) bool {
+	now := time.Now()
+	if len(addr) > 0 && n.bans.IsBanned(addr, now) {
+		return true
+	}
+	host := remoteHost(ctx)
+	return len(host) > 0 && n.bans.IsBanned(host, now)
+}
+
+func remoteHost(ctx context.Context) string {
+	p, ok := grpcpeer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+func isLoopback(host string) bool {
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// requireAdmin only lets clients on the same machine use the admin RPCs.
+func requireAdmin(ctx context.Context) error {
+	if _, ok := grpcpeer.FromContext(ctx); !ok {
+		return nil
+	}
+	if host := remoteHost(ctx); !isLoopback(host) {
+		return fmt.Errorf("admin rpc from %s denied", host)
+	}
+	return nil
+}
+
+func (n *Node) ListBans(ctx context.Context, _ *proto.Ack) (*proto.Bans, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	resp := &proto.Bans{}
+	for _, ban := range n.bans.List(time.Now()) {
+		resp.Bans = append(resp.Bans, &proto.BanInfo{
+			Addr:   ban.Addr,
+			Reason: ban.Reason,
+			Until:  ban.Until.Unix(),
+		})
+	}
+	return resp, nil
+}
+
+func (n *Node) BanPeer(ctx context.Context, req *proto.BanRequest) (*proto.Ack, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if len(req.Addr) == 0 {
+		return nil, fmt.Errorf("missing address")
+	}
+	duration := banDuration
+	if req.Seconds > 0 {
+		duration = min(time.Duration(req.Seconds)*time.Second, maxBanDuration)
+	}
+	reason := req.Reason
+	if len(reason) == 0 {
+		reason = "banned by admin"
+	}
+	n.bans.Ban(req.Addr, time.Now().Add(duration), reason)
+	n.deletePeer(req.Addr)
+	n.logger.Infow("banning peer", "we", n.ListenAddr, "remote", req.Addr, "reason", reason)
+	return &proto.Ack{}, nil
+}
+
+func (n *Node) UnbanPeer(ctx context.Context, req *proto.BanRequest) (*proto.Ack, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if !n.bans.Unban(req.Addr) {
+		return nil, fmt.Errorf("%s is not banned", req.Addr)
+	}
+	return &proto.Ack{}, nil
+}
@@ -0,0 +1,181 @@
+package node
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/64bitAryan/blocker/proto"
+	"github.com/64bitAryan/blocker/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	grpcpeer "google.golang.org/grpc/peer"
+)
+
+// invalidTx returns a distinct transaction with a negative output.
+func invalidTx() *proto.Transaction {
+	return &proto.Transaction{
+		Version: 1,
+		Outputs: []*proto.TxOutput{
+			{Amount: -1, Address: util.RandomHash()},
+		},
+	}
+}
+
+func TestBanListPenalize(t *testing.T) {
+	var (
+		bans = NewBanList()
+		now  = time.Now()
+	)
+	assert.False(t, bans.Penalize(":4000", banThreshold-1, "spam", now))
+	assert.Equal(t, banThreshold-1, bans.Score(":4000", now))
+	assert.False(t, bans.IsBanned(":4000", now))
+
+	// good behaviour lets the score decay
+	later := now.Add(scoreDecayInterval * 10)
+	assert.Equal(t, banThreshold-11, bans.Score(":4000", later))
+	assert.False(t, bans.Penalize(":4000", 10, "spam", later))
+	assert.True(t, bans.Penalize(":4000", 1, "spam", later))
+	assert.True(t, bans.IsBanned(":4000", later))
+	assert.Zero(t, bans.Score(":4000", later))
+
+	bans.Ban(":5000", later.Add(time.Minute), "admin")
+	list := bans.List(later)
+	require.Len(t, list, 2)
+	assert.Equal(t, ":4000", list[0].Addr)
+	assert.Equal(t, "spam", list[0].Reason)
+
+	// bans expire
+	assert.False(t, bans.IsBanned(":5000", later.Add(time.Minute*2)))
+	assert.Len(t, bans.List(later.Add(time.Minute*2)), 1)
+	assert.True(t, bans.Unban(":4000"))
+	assert.False(t, bans.Unban(":4000"))
+}
+
+func TestInvalidTxsBanPeer(t *testing.T) {
+	var (
+		n      = NewNode(ServerConfig{ListenAddr: ":3000"})
+		sender = &fakeNodeClient{alive: true}
+	)
+	addFakePeer(n, ":4000", sender)
+	p := n.getPeer(":4000")
+
+	for i := 0; i < banThreshold/penaltyInvalidTx; i++ {
+		tx := invalidTx()
+		sender.items = &proto.Items{Transactions: []*proto.Transaction{tx}}
+		n.fetch(p, []*proto.InvItem{txInvItem(tx)})
+		assert.False(t, n.mempool.Has(tx))
+	}
+	assert.Empty(t, n.getPeerList())
+	assert.True(t, n.bans.IsBanned(":4000", time.Now()))
+	assert.False(t, n.canConnectWith(":4000"))
+
+	remote := NewNode(ServerConfig{ListenAddr: ":4000"})
+	_, err := n.Handshake(context.Background(), signedVersionFor(t, remote, n))
+	assert.ErrorContains(t, err, "banned")
+}
+
+func TestStaleTxsAreNotPenalized(t *testing.T) {
+	var (
+		n      = NewNode(ServerConfig{ListenAddr: ":3000"})
+		sender = &fakeNodeClient{alive: true}
+		tx     = &proto.Transaction{
+			Version: 1,
+			Inputs: []*proto.TxInput{
+				{PrevTxHash: util.RandomHash()},
+			},
+		}
+	)
+	addFakePeer(n, ":4000", sender)
+	defer n.deletePeer(":4000")
+
+	sender.items = &proto.Items{Transactions: []*proto.Transaction{tx}}
+	n.fetch(n.getPeer(":4000"), []*proto.InvItem{txInvItem(tx)})
+	assert.False(t, n.mempool.Has(tx))
+	assert.Zero(t, n.bans.Score(":4000", time.Now()))
+}
+
+func TestUnrequestedItemsArePenalized(t *testing.T) {
+	var (
+		n      = NewNode(ServerConfig{ListenAddr: ":3000"})
+		tx     = &proto.Transaction{Version: 42}
+		sender = &fakeNodeClient{alive: true, items: &proto.Items{Transactions: []*proto.Transaction{tx}}}
+	)
+	addFakePeer(n, ":4000", sender)
+	defer n.deletePeer(":4000")
+
+	n.fetch(n.getPeer(":4000"), []*proto.InvItem{})
+	assert.False(t, n.mempool.Has(tx))
+	assert.Equal(t, penaltySpam, n.bans.Score(":4000", time.Now()))
+
+	_, err := n.Announce(context.Background(), &proto.Inventory{
+		Items:      make([]*proto.InvItem, maxInventoryItems+1),
+		ListenAddr: ":4000",
+	})
+	assert.NotNil(t, err)
+}
+
+func TestHandleInvalidTransaction(t *testing.T) {
+	var (
+		n      = NewNode(ServerConfig{ListenAddr: ":3000", TrustLoopback: true})
+		client = grpcpeer.NewContext(context.Background(), &grpcpeer.Peer{
+			Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 50000},
+		})
+		local = grpcpeer.NewContext(context.Background(), &grpcpeer.Peer{
+			Addr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 50000},
+		})
+	)
+	for i := 0; i < banThreshold/penaltyInvalidTx; i++ {
+		_, err := n.HandleTransaction(client, invalidTx())
+		assert.NotNil(t, err)
+		_, err = n.HandleTransaction(local, invalidTx())
+		assert.NotNil(t, err)
+	}
+	assert.True(t, n.bans.IsBanned("10.0.0.1", time.Now()))
+	assert.False(t, n.bans.IsBanned("127.0.0.1", time.Now()))
+
+	_, err := n.HandleTransaction(client, &proto.Transaction{Version: 42})
+	assert.ErrorContains(t, err, "banned")
+	_, err = n.HandleTransaction(local, &proto.Transaction{Version: 42})
+	assert.Nil(t, err)
+
+	// the loopback is only trusted when asked to
+	n = NewNode(ServerConfig{ListenAddr: ":3000"})
+	for i := 0; i < banThreshold/penaltyInvalidTx; i++ {
+		_, err := n.HandleTransaction(local, invalidTx())
+		assert.NotNil(t, err)
+	}
+	assert.True(t, n.bans.IsBanned("127.0.0.1", time.Now()))
+}
+
+func TestAdminBanRPCs(t *testing.T) {
+	var (
+		n      = NewNode(ServerConfig{ListenAddr: ":3000"})
+		remote = grpcpeer.NewContext(context.Background(), &grpcpeer.Peer{
+			Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 50000},
+		})
+		ctx = context.Background()
+	)
+	addFakePeer(n, ":4000", &fakeNodeClient{alive: true})
+
+	_, err := n.BanPeer(remote, &proto.BanRequest{Addr: ":4000"})
+	assert.NotNil(t, err)
+
+	_, err = n.BanPeer(ctx, &proto.BanRequest{Addr: ":4000", Seconds: 60, Reason: "testing"})
+	require.Nil(t, err)
+	assert.Empty(t, n.getPeerList())
+
+	bans, err := n.ListBans(ctx, &proto.Ack{})
+	require.Nil(t, err)
+	require.Len(t, bans.Bans, 1)
+	assert.Equal(t, ":4000", bans.Bans[0].Addr)
+	assert.Equal(t, "testing", bans.Bans[0].Reason)
+	assert.InDelta(t, time.Now().Add(time.Minute).Unix(), bans.Bans[0].Until, 2)
+
+	_, err = n.UnbanPeer(ctx, &proto.BanRequest{Addr: ":4000"})
+	require.Nil(t, err)
+	_, err = n.UnbanPeer(ctx, &proto.BanRequest{Addr: ":4000"})
+	assert.NotNil(t, err)
+	assert.True(t, n.canConnectWith(":4000"))
+}
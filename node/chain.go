@@ -3,16 +3,36 @@ package node
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"sync"
+	"time"
 
 	"github.com/64bitAryan/blocker/crypto"
 	"github.com/64bitAryan/blocker/proto"
+	"github.com/64bitAryan/blocker/script"
 	"github.com/64bitAryan/blocker/types"
 )
 
+// ErrOrphanBlock is returned for blocks that do not build on the tip of the
+// chain, which honest nodes send too when they are on another branch.
+var ErrOrphanBlock = errors.New("invalid previous block hash")
+
+// ErrSpentOutput and ErrUnknownOutput are returned for transactions whose
+// inputs we can not spend, honest nodes send them too when they saw another
+// transaction spending the same output first or know outputs we do not.
+var (
+	ErrSpentOutput   = errors.New("output already spent")
+	ErrUnknownOutput = errors.New("unknown output")
+)
+
 const godSeed = "54967bdaf7dacbf0adf004ad2ddb1196073239bb0b83bf587c21edf503a3a90e"
 
 type HeaderList struct {
+	lock    sync.RWMutex
 	headers []*proto.Header
 }
 
@@ -23,14 +43,34 @@ func NewHeaderList() *HeaderList {
 }
 
 func (list *HeaderList) Add(h *proto.Header) {
+	list.lock.Lock()
+	defer list.lock.Unlock()
 	list.headers = append(list.headers, h)
 }
 
 func (list *HeaderList) Get(index int) *proto.Header {
-	if index > list.Height() {
-		panic("index too heigh")
+	h, ok := list.Lookup(index)
+	if !ok {
+		panic("index out of range")
 	}
-	return list.headers[index]
+	return h
+}
+
+// Lookup returns the header at index, ok is false if there is none.
+func (list *HeaderList) Lookup(index int) (*proto.Header, bool) {
+	list.lock.RLock()
+	defer list.lock.RUnlock()
+	if index < 0 || index >= len(list.headers) {
+		return nil, false
+	}
+	return list.headers[index], true
+}
+
+// Truncate drops the headers above height.
+func (list *HeaderList) Truncate(height int) {
+	list.lock.Lock()
+	defer list.lock.Unlock()
+	list.headers = list.headers[:height+1]
 }
 
 func (list *HeaderList) Height() int {
@@ -39,6 +79,8 @@ func (list *HeaderList) Height() int {
 }
 
 func (list *HeaderList) Len() int {
+	list.lock.RLock()
+	defer list.lock.RUnlock()
 	return len(list.headers)
 }
 
@@ -46,30 +88,127 @@ type UTXO struct {
 	Hash     string
 	OutIndex int
 	Amount   int64
-	Spent    bool
+	// Address is the hex address the output pays to, empty when its lock
+	// script does not pay to an address
+	Address string
+	Spent   bool
 }
 
 type Chain struct {
+	// serializes validating and adding blocks
+	lock       sync.Mutex
 	txStore    TXStorer
 	blockstore BlockStorer
 	utxoStore  UTXOStorer
 	headers    *HeaderList
+	engine     Engine
+	// cumulative work of the branch ending in a block, by hex block hash,
+	// for the blocks of every branch
+	work map[string]*big.Int
+	// closed and replaced whenever the tip changes
+	tipChanged chan struct{}
+	// validators schedules who may sign the blocks, any key may when nil
+	validators *ValidatorSet
+	// the sets that validated earlier heights, oldest first
+	history    []validatorEpoch
+	governance *governance
+	// blocks up to this height can never be reverted
+	finalized int
+	params    ChainParams
+}
+
+// validatorEpoch is a validator set and the first height it validated.
+type validatorEpoch struct {
+	from int64
+	set  *ValidatorSet
 }
 
+// NewChain starts a development chain, see DevGenesis.
 func NewChain(bs BlockStorer, txStore TXStorer) *Chain {
+	return NewChainWithParams(bs, txStore, DefaultChainParams())
+}
+
+// NewChainWithParams starts a development chain with params, the unset
+// ones take their default. It panics on negative params.
+func NewChainWithParams(bs BlockStorer, txStore TXStorer, params ChainParams) *Chain {
+	chain, err := NewChainFromGenesis(bs, txStore, DevGenesis(params))
+	if err != nil {
+		panic(err)
+	}
+	return chain
+}
+
+// NewChainFromGenesis starts the chain of g, with its validator set.
+func NewChainFromGenesis(bs BlockStorer, txStore TXStorer, g *Genesis) (*Chain, error) {
+	genesis, err := g.Block()
+	if err != nil {
+		return nil, err
+	}
+	if !types.VerifyBlock(genesis) {
+		return nil, fmt.Errorf("invalid genesis block signature")
+	}
+	params, err := g.ChainParams()
+	if err != nil {
+		return nil, err
+	}
+	validators, err := g.validators()
+	if err != nil {
+		return nil, err
+	}
 	chain := &Chain{
 		blockstore: bs,
 		txStore:    txStore,
 		utxoStore:  NewMemoryUTXOStore(),
 		headers:    NewHeaderList(),
+		engine:     NewAuthorityEngine(params.BlockTime),
+		work:       make(map[string]*big.Int),
+		tipChanged: make(chan struct{}),
+		governance: newGovernance(),
+		params:     params,
 	}
-	chain.addBlock(createGenesisBlock())
-	return chain
-
+	if err := chain.addBlock(genesis); err != nil {
+		return nil, err
+	}
+	if len(validators) > 0 {
+		chain.SetValidatorSet(NewValidatorSet(params.BlockTime, validators...))
+	}
+	return chain, nil
 }
+
+// addBlock adds the validated block b on top of the tip.
 func (c *Chain) addBlock(b *proto.Block) error {
+	if err := c.applyBlock(b); err != nil {
+		return err
+	}
+	// the block becomes visible by height only once it is fully stored
 	c.headers.Add(b.Header)
+	if depth := c.engine.FinalityDepth(); depth > 0 {
+		c.finalized = max(c.finalized, c.Height()-depth)
+	}
+	close(c.tipChanged)
+	c.tipChanged = make(chan struct{})
+	return nil
+}
+
+// spendInputs marks the outputs tx spends, so that no later transaction
+// can spend them again.
+func (c *Chain) spendInputs(tx *proto.Transaction) error {
+	for _, input := range tx.Inputs {
+		utxo, err := c.utxoStore.Get(inputKey(input))
+		if err != nil {
+			return err
+		}
+		spent := *utxo
+		spent.Spent = true
+		if err := c.utxoStore.Put(&spent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
+// applyBlock stores b and applies it to the state of the chain.
+func (c *Chain) applyBlock(b *proto.Block) error {
 	for _, tx := range b.Transactions {
 
 		if err := c.txStore.Put(tx); err != nil {
@@ -77,11 +216,15 @@ func (c *Chain) addBlock(b *proto.Block) error {
 		}
 		hash := hex.EncodeToString(types.HashTransaction(tx))
 
+		if err := c.spendInputs(tx); err != nil {
+			return err
+		}
 		for it, output := range tx.Outputs {
 			utxo := &UTXO{
 				Hash:     hash,
 				OutIndex: it,
 				Amount:   output.Amount,
+				Address:  outputAddress(output),
 				Spent:    false,
 			}
 			if err := c.utxoStore.Put(utxo); err != nil {
@@ -90,30 +233,305 @@ func (c *Chain) addBlock(b *proto.Block) error {
 		}
 	}
 
-	return c.blockstore.Put(b)
+	if err := c.blockstore.Put(b); err != nil {
+		return err
+	}
+	for _, tx := range b.Transactions {
+		if tx.Governance != nil {
+			c.applyGovernance(tx, int64(b.Header.Height))
+		}
+	}
+	for _, ev := range b.Evidence {
+		c.slash(ev)
+	}
+	c.endBlock(int64(b.Header.Height))
+
+	work := new(big.Int)
+	if b.Header.Height > 0 {
+		work.Add(c.workOf(b.Header.PrevHash), c.engine.Work(b.Header))
+	}
+	c.work[hex.EncodeToString(types.HashBlock(b))] = work
+	return nil
 }
 
+// AddBlock adds b to the chain. Blocks building on another branch than
+// ours are kept, and the chain switches to their branch once it has more
+// work than ours.
 func (c *Chain) AddBlock(b *proto.Block) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	tip := c.headers.Get(c.Height())
+	if !bytes.Equal(b.Header.PrevHash, types.HashHeader(tip)) && c.HasBlock(b.Header.PrevHash) {
+		return c.addSideBlock(b)
+	}
 	if err := c.ValidateBlock(b); err != nil {
 		return err
 	}
 	return c.addBlock(b)
 }
 
+func (c *Chain) addSideBlock(b *proto.Block) error {
+	parent, err := c.GetBlockByHash(b.Header.PrevHash)
+	if err != nil {
+		return err
+	}
+	if !types.VerifyBlock(b) {
+		return fmt.Errorf("invalid block signature")
+	}
+	if b.Header.Height != parent.Header.Height+1 {
+		return fmt.Errorf("block height (%d) does not follow its parent (%d)", b.Header.Height, parent.Header.Height)
+	}
+	if int(b.Header.Height) <= c.finalized {
+		return fmt.Errorf("block height (%d) is final already - finalized height (%d)", b.Header.Height, c.finalized)
+	}
+	if err := c.params.checkBlock(b); err != nil {
+		return err
+	}
+	if err := c.engine.VerifySeal(c, c.validators, b, parent.Header, time.Now()); err != nil {
+		return err
+	}
+	if err := c.blockstore.Put(b); err != nil {
+		return err
+	}
+	work := new(big.Int).Add(c.workOf(b.Header.PrevHash), c.engine.Work(b.Header))
+	c.work[hex.EncodeToString(types.HashBlock(b))] = work
+	if work.Cmp(c.workOf(types.HashHeader(c.headers.Get(c.Height())))) <= 0 {
+		return nil
+	}
+	return c.reorg(b)
+}
+
+// reorg switches to the branch ending in tip. The state is rebuilt from
+// genesis, which is fine for the short chains of demo networks.
+func (c *Chain) reorg(tip *proto.Block) error {
+	var (
+		branch = []*proto.Block{tip}
+		fork   int
+	)
+	for {
+		parent, err := c.GetBlockByHash(branch[len(branch)-1].Header.PrevHash)
+		if err != nil {
+			return err
+		}
+		if c.isCanonical(parent.Header) {
+			fork = int(parent.Header.Height)
+			break
+		}
+		branch = append(branch, parent)
+	}
+	if fork < c.finalized {
+		return fmt.Errorf("branch forks below the finalized height (%d)", c.finalized)
+	}
+
+	reverted := []*proto.Block{}
+	for height := fork + 1; height <= c.Height(); height++ {
+		b, err := c.GetBlockByHeight(height)
+		if err != nil {
+			return err
+		}
+		reverted = append(reverted, b)
+	}
+	if err := c.rewind(fork); err != nil {
+		return err
+	}
+	for i := len(branch) - 1; i >= 0; i-- {
+		err := c.ValidateBlock(branch[i])
+		if err == nil {
+			err = c.addBlock(branch[i])
+		}
+		if err != nil {
+			// back to our branch, it was valid
+			if err := c.rewind(fork); err != nil {
+				return err
+			}
+			for _, b := range reverted {
+				if err := c.addBlock(b); err != nil {
+					return err
+				}
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// rewind rebuilds the state of the chain up to height from genesis and
+// drops the blocks above it.
+func (c *Chain) rewind(height int) error {
+	c.utxoStore = NewMemoryUTXOStore()
+	c.governance = newGovernance()
+	if len(c.history) > 0 {
+		c.history = c.history[:1]
+		c.validators = c.history[0].set
+	}
+	for h := 0; h <= height; h++ {
+		b, err := c.GetBlockByHeight(h)
+		if err != nil {
+			return err
+		}
+		if err := c.applyBlock(b); err != nil {
+			return err
+		}
+	}
+	c.headers.Truncate(height)
+	return nil
+}
+
+func (c *Chain) isCanonical(h *proto.Header) bool {
+	if int(h.Height) > c.Height() {
+		return false
+	}
+	return bytes.Equal(types.HashHeader(c.headers.Get(int(h.Height))), types.HashHeader(h))
+}
+
+func (c *Chain) workOf(hash []byte) *big.Int {
+	work, ok := c.work[hex.EncodeToString(hash)]
+	if !ok {
+		return new(big.Int)
+	}
+	return work
+}
+
+// TipChanged returns a channel that is closed once the tip of the chain
+// changes.
+func (c *Chain) TipChanged() <-chan struct{} {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.tipChanged
+}
+
+// Close flushes and closes the stores that need it.
+func (c *Chain) Close() error {
+	for _, store := range []any{c.blockstore, c.txStore, c.utxoStore} {
+		if closer, ok := store.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SetEngine replaces the consensus engine, before any block is added.
+func (c *Chain) SetEngine(engine Engine) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.engine = engine
+}
+
+func (c *Chain) Engine() Engine {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.engine
+}
+
+// TotalWork returns the work of the chain, the sum of the work of its
+// blocks after genesis.
+func (c *Chain) TotalWork() *big.Int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return new(big.Int).Set(c.workOf(types.HashHeader(c.headers.Get(c.Height()))))
+}
+
+func (c *Chain) SetValidatorSet(vs *ValidatorSet) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.validators = vs
+	c.history = []validatorEpoch{{from: int64(c.Height() + 1), set: vs}}
+}
+
+func (c *Chain) ValidatorSet() *ValidatorSet {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.validators
+}
+
+// ValidatorSetAt returns the set that validates the block at height, nil
+// when there was none.
+func (c *Chain) ValidatorSetAt(height int64) *ValidatorSet {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.validatorSetAt(height)
+}
+
+func (c *Chain) validatorSetAt(height int64) *ValidatorSet {
+	for i := len(c.history) - 1; i >= 0; i-- {
+		if c.history[i].from <= height {
+			return c.history[i].set
+		}
+	}
+	return nil
+}
+
+func (c *Chain) HasBlock(hash []byte) bool {
+	_, err := c.GetBlockByHash(hash)
+	return err == nil
+}
+
+// ListUnspent returns the unspent outputs paying to address.
+func (c *Chain) ListUnspent(address crypto.Address) ([]*UTXO, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.utxoStore.ListUnspent(address.String())
+}
+
+// GetBalance returns the sum of the unspent outputs paying to address.
+func (c *Chain) GetBalance(address crypto.Address) (int64, error) {
+	utxos, err := c.ListUnspent(address)
+	if err != nil {
+		return 0, err
+	}
+	balance := int64(0)
+	for _, utxo := range utxos {
+		balance += utxo.Amount
+	}
+	return balance, nil
+}
+
+func (c *Chain) GetTransactionByHash(hash []byte) (*proto.Transaction, error) {
+	return c.txStore.Get(hex.EncodeToString(hash))
+}
+
+// Params returns the parameters of the genesis block.
+func (c *Chain) Params() ChainParams {
+	return c.params
+}
+
+func (c *Chain) ChainID() string {
+	return c.headers.Get(0).ChainID
+}
+
+func (c *Chain) GenesisHash() []byte {
+	return types.HashHeader(c.headers.Get(0))
+}
+
 func (c *Chain) Height() int {
 	return c.headers.Height()
 }
 
+// GetHeader returns the header of a stored block of any branch.
+func (c *Chain) GetHeader(hash []byte) (*proto.Header, error) {
+	b, err := c.GetBlockByHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	return b.Header, nil
+}
+
 func (c *Chain) GetBlockByHash(hash []byte) (*proto.Block, error) {
 	hashHex := hex.EncodeToString(hash)
 	return c.blockstore.Get(hashHex)
 }
 
 func (c *Chain) GetBlockByHeight(height int) (*proto.Block, error) {
-	if c.Height() < height {
+	if height < 0 {
+		return nil, fmt.Errorf("given height (%d) is negative", height)
+	}
+	header, ok := c.headers.Lookup(height)
+	if !ok {
 		return nil, fmt.Errorf("given height (%d) too high - height (%d)", height, c.Height())
 	}
-	header := c.headers.Get(height)
 	hash := types.HashHeader(header)
 	return c.GetBlockByHash(hash)
 }
@@ -123,79 +541,186 @@ func (c *Chain) ValidateBlock(b *proto.Block) error {
 		return fmt.Errorf("invalid block signature")
 	}
 
+	if int(b.Header.Height) <= c.finalized {
+		return fmt.Errorf("block height (%d) is final already - finalized height (%d)", b.Header.Height, c.finalized)
+	}
+	if err := c.params.checkBlock(b); err != nil {
+		return err
+	}
 	currBlock, err := c.GetBlockByHeight(c.Height())
 	if err != nil {
 		return err
 	}
 	hash := types.HashBlock(currBlock)
 	if !bytes.Equal(hash, b.Header.PrevHash) {
-		return fmt.Errorf("invalid previous block hash")
+		return ErrOrphanBlock
+	}
+	if int(b.Header.Height) != c.Height()+1 {
+		return fmt.Errorf("block height (%d) does not follow the tip (%d)", b.Header.Height, c.Height())
+	}
+	if err := c.engine.VerifySeal(c, c.validators, b, currBlock.Header, time.Now()); err != nil {
+		return err
+	}
+	if err := c.validateBlockEvidence(b); err != nil {
+		return err
 	}
 
-	for _, tx := range b.Transactions {
-		if err := c.ValidateTransaction(tx); err != nil {
+	height := int64(c.Height() + 1)
+	if len(b.Transactions) >= batchVerifyMinTxs {
+		return c.validateTransactionsBatch(b.Transactions, height, b.Header.Timestamp)
+	}
+	return c.validateTransactions(b.Transactions, height, b.Header.Timestamp)
+}
+
+// validateTransactions validates the transactions of a block in order, an
+// output may be spent by only one of them.
+func (c *Chain) validateTransactions(txx []*proto.Transaction, height int64, timestamp int64) error {
+	spent := make(map[string]bool)
+	for _, tx := range txx {
+		if err := c.validateTransaction(tx, height, timestamp, nil, spent); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// ValidateTransaction validates tx as if it was included in the next block.
 func (c *Chain) ValidateTransaction(tx *proto.Transaction) error {
-	// Verify the signature
-	if !types.VerifyTransaction(tx) {
-		return fmt.Errorf("invalid tx signature")
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if err := c.params.checkTx(tx); err != nil {
+		return err
 	}
-	// Check if all the inputs are unspent
+	return c.validateTransaction(tx, int64(c.Height()+1), time.Now().UnixNano(), nil, nil)
+}
+
+// validateTransaction validates tx against the unspent outputs. With a
+// batch the signature checks are deferred into it, tx is only valid once
+// the batch verifies too. spent holds the outputs spent by the transactions
+// before tx in its block, the inputs of a valid tx are added to it.
+func (c *Chain) validateTransaction(tx *proto.Transaction, height int64, timestamp int64, batch *crypto.BatchVerifier, spent map[string]bool) error {
 	var (
 		hash    = hex.EncodeToString(types.HashTransaction(tx))
 		nInputs = len(tx.Inputs)
+		ctx     = script.Context{
+			SigHash: types.SigHash(tx),
+			Height:  height,
+			Time:    timestamp,
+		}
+	)
+	if tx.Governance != nil {
+		return c.validateGovernance(tx, batch)
+	}
+	if batch != nil {
+		ctx.CheckSig = func(sig []byte, pubKey []byte, msg []byte) bool {
+			if len(sig) != crypto.SignatureLen || len(pubKey) != crypto.PubKeyLen {
+				return false
+			}
+			batch.Add(crypto.PublicKeyFromBytes(pubKey), msg, crypto.SignatureFromBytes(sig))
+			return true
+		}
+	}
+	// Check if all the inputs are unspent and unlocked by the spender
+	var (
+		sumInput = int64(0)
+		inputs   = make(map[string]bool, nInputs)
 	)
-	sumInput := 0
 	for i := 0; i < nInputs; i++ {
-		prevHash := hex.EncodeToString(tx.Inputs[i].PrevTxHash)
-		key := fmt.Sprintf("%s-%d", prevHash, i)
+		input := tx.Inputs[i]
+		prevHash := hex.EncodeToString(input.PrevTxHash)
+		key := inputKey(input)
+		if inputs[key] {
+			return fmt.Errorf("input %d of tx %s spends %s twice", i, hash, key)
+		}
+		if spent[key] {
+			return fmt.Errorf("input %d of tx %s: %w in the block", i, hash, ErrSpentOutput)
+		}
+		inputs[key] = true
 		utxo, err := c.utxoStore.Get(key)
-		sumInput += int(utxo.Amount)
 		if err != nil {
-			return err
+			return fmt.Errorf("input %d of tx %s: %w: %v", i, hash, ErrUnknownOutput, err)
 		} else if utxo.Spent {
-			return fmt.Errorf("input %d of tx %s is already spent", i, hash)
+			return fmt.Errorf("input %d of tx %s: %w", i, hash, ErrSpentOutput)
+		}
+		if sumInput, err = addAmount(sumInput, utxo.Amount); err != nil {
+			return fmt.Errorf("inputs of tx %s: %w", hash, err)
+		}
+
+		prevTx, err := c.txStore.Get(prevHash)
+		if err != nil {
+			return err
+		}
+		output := prevTx.Outputs[input.PrevOutIndex]
+		if err := script.Execute(unlockScript(input), lockScript(output), ctx); err != nil {
+			return fmt.Errorf("input %d of tx %s: %w", i, hash, err)
 		}
 	}
 
-	sumOutput := 0
-	for _, output := range tx.Outputs {
-		sumOutput += int(output.Amount)
+	sumOutput := int64(0)
+	for i, output := range tx.Outputs {
+		if output.Amount <= 0 {
+			return fmt.Errorf("output %d of tx %s has a non positive amount (%d)", i, hash, output.Amount)
+		}
+		var err error
+		if sumOutput, err = addAmount(sumOutput, output.Amount); err != nil {
+			return fmt.Errorf("outputs of tx %s: %w", hash, err)
+		}
 	}
 
 	if sumInput < sumOutput {
 		return fmt.Errorf("invalid tx: insufficient balance :: input sum (%d), output sum (%d)", sumInput, sumOutput)
 	}
 
+	if spent != nil {
+		for key := range inputs {
+			spent[key] = true
+		}
+	}
 	return nil
 }
 
-func createGenesisBlock() *proto.Block {
-	privKey := crypto.NewPrivateKeyFromSeedStr(godSeed)
-	block := &proto.Block{
-		Header: &proto.Header{
-			Version: 1,
-		},
+// inputKey is the key of the unspent output spent by input.
+func inputKey(input *proto.TxInput) string {
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(input.PrevTxHash), input.PrevOutIndex)
+}
+
+// addAmount adds two non negative amounts, failing when the sum overflows.
+func addAmount(sum int64, amount int64) (int64, error) {
+	if amount < 0 || amount > math.MaxInt64-sum {
+		return 0, fmt.Errorf("amount overflow")
 	}
+	return sum + amount, nil
+}
 
-	tx := &proto.Transaction{
-		Version: 1,
-		Inputs:  []*proto.TxInput{},
-		Outputs: []*proto.TxOutput{
-			{
-				Amount:  1000,
-				Address: privKey.Public().Address().Bytes(),
-			},
-		},
+func unlockScript(input *proto.TxInput) []byte {
+	if len(input.UnlockScript) > 0 {
+		return input.UnlockScript
 	}
+	return script.UnlockPayToAddress(input.Signature, input.PublicKey)
+}
 
-	block.Transactions = append(block.Transactions, tx)
-	types.SignBlock(privKey, block)
+func lockScript(output *proto.TxOutput) []byte {
+	if len(output.LockScript) > 0 {
+		return output.LockScript
+	}
+	return script.PayToAddress(output.Address)
+}
+
+// outputAddress is the address an output counts for in balances, none when
+// its lock script does not pay to its address.
+func outputAddress(output *proto.TxOutput) string {
+	if len(output.Address) == 0 {
+		return ""
+	}
+	if len(output.LockScript) > 0 && !bytes.Equal(output.LockScript, script.PayToAddress(output.Address)) {
+		return ""
+	}
+	return hex.EncodeToString(output.Address)
+}
 
-	return block
+// GenesisKey returns the key signing every genesis block and owning the
+// output of the development genesis, it is public and must only be used in
+// tests.
+func GenesisKey() *crypto.PrivateKeys {
+	return crypto.NewPrivateKeyFromSeedStr(godSeed)
 }
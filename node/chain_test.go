@@ -1,10 +1,15 @@
 package node
 
 import (
+	"encoding/hex"
+	"math"
+	"math/big"
 	"testing"
+	"time"
 
 	"github.com/64bitAryan/blocker/crypto"
 	"github.com/64bitAryan/blocker/proto"
+	"github.com/64bitAryan/blocker/script"
 	"github.com/64bitAryan/blocker/types"
 	"github.com/64bitAryan/blocker/util"
 	"github.com/stretchr/testify/assert"
@@ -16,11 +21,21 @@ func randomBlock(t *testing.T, chain *Chain) *proto.Block {
 	b := util.RandomBlock()
 	prevBlock, err := chain.GetBlockByHeight(chain.Height())
 	require.Nil(t, err)
+	b.Header.Height = int32(chain.Height() + 1)
 	b.Header.PrevHash = types.HashBlock(prevBlock)
 	types.SignBlock(privKey, b)
 	return b
 }
 
+// blockOn returns a signed block on top of parent.
+func blockOn(parent *proto.Block) *proto.Block {
+	b := util.RandomBlock()
+	b.Header.Height = parent.Header.Height + 1
+	b.Header.PrevHash = types.HashBlock(parent)
+	types.SignBlock(crypto.GeneratePrivateKey(), b)
+	return b
+}
+
 func TestNewChain(t *testing.T) {
 	chain := NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
 	require.Equal(t, chain.Height(), 0)
@@ -135,3 +150,270 @@ func TestAddBlocksWithTxInsuffucientFunds(t *testing.T) {
 
 	require.NotNil(t, chain.AddBlock(block))
 }
+
+func TestAddBlockWithMultiSigTx(t *testing.T) {
+	var (
+		chain    = NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+		privKey  = crypto.NewPrivateKeyFromSeedStr(godSeed)
+		signers  = []*crypto.PrivateKeys{crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey()}
+		lock     = script.MultiSig(2, [][]byte{signers[0].Public().Bytes(), signers[1].Public().Bytes()})
+		receiver = crypto.GeneratePrivateKey().Public().Address().Bytes()
+	)
+
+	prevTx, err := chain.txStore.Get("0ff6af1c9aa971ef969f2cf72b2cfb8bf21d52c80c131d959de4d8edc6687e21")
+	require.Nil(t, err)
+
+	lockTx := &proto.Transaction{
+		Version: 1,
+		Inputs: []*proto.TxInput{
+			{
+				PrevTxHash:   types.HashTransaction(prevTx),
+				PrevOutIndex: 0,
+				PublicKey:    privKey.Public().Bytes(),
+			},
+		},
+		Outputs: []*proto.TxOutput{
+			{
+				Amount:     1000,
+				LockScript: lock,
+			},
+		},
+	}
+	lockTx.Inputs[0].Signature = types.SignTransaction(privKey, lockTx).Bytes()
+
+	block := randomBlock(t, chain)
+	block.Transactions = append(block.Transactions, lockTx)
+	types.SignBlock(privKey, block)
+	require.Nil(t, chain.AddBlock(block))
+
+	spendTx := &proto.Transaction{
+		Version: 1,
+		Inputs: []*proto.TxInput{
+			{
+				PrevTxHash:   types.HashTransaction(lockTx),
+				PrevOutIndex: 0,
+			},
+		},
+		Outputs: []*proto.TxOutput{
+			{
+				Amount:  1000,
+				Address: receiver,
+			},
+		},
+	}
+
+	// a single signature does not satisfy the 2 of 2 lock script
+	spendTx.Inputs[0].UnlockScript = script.UnlockMultiSig([][]byte{
+		types.SignTransaction(signers[0], spendTx).Bytes(),
+	})
+	require.NotNil(t, chain.ValidateTransaction(spendTx))
+
+	spendTx.Inputs[0].UnlockScript = script.UnlockMultiSig([][]byte{
+		types.SignTransaction(signers[0], spendTx).Bytes(),
+		types.SignTransaction(signers[1], spendTx).Bytes(),
+	})
+	require.Nil(t, chain.ValidateTransaction(spendTx))
+
+	block = randomBlock(t, chain)
+	block.Transactions = append(block.Transactions, spendTx)
+	types.SignBlock(privKey, block)
+	require.Nil(t, chain.AddBlock(block))
+}
+
+func TestReorg(t *testing.T) {
+	chain := NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	var (
+		a1 = blockOn(genesis)
+		a2 = blockOn(a1)
+		b1 = blockOn(genesis)
+		b2 = blockOn(b1)
+		b3 = blockOn(b2)
+	)
+	require.Nil(t, chain.AddBlock(a1))
+	require.Nil(t, chain.AddBlock(a2))
+
+	// a branch as heavy as ours is kept aside
+	require.Nil(t, chain.AddBlock(b1))
+	require.Nil(t, chain.AddBlock(b2))
+	tip, err := chain.GetBlockByHeight(2)
+	require.Nil(t, err)
+	assert.Equal(t, types.HashBlock(a2), types.HashBlock(tip))
+
+	changed := chain.TipChanged()
+	require.Nil(t, chain.AddBlock(b3))
+	assert.Equal(t, 3, chain.Height())
+	assert.Equal(t, big.NewInt(3), chain.TotalWork())
+	for _, b := range []*proto.Block{b1, b2, b3} {
+		onChain, err := chain.GetBlockByHeight(int(b.Header.Height))
+		require.Nil(t, err)
+		assert.Equal(t, types.HashBlock(b), types.HashBlock(onChain))
+	}
+	select {
+	case <-changed:
+	default:
+		t.Fatal("tip change not notified")
+	}
+
+	// the old branch is still known and can win again
+	a3 := blockOn(a2)
+	a4 := blockOn(a3)
+	require.Nil(t, chain.AddBlock(a3))
+	require.Nil(t, chain.AddBlock(a4))
+	tip, err = chain.GetBlockByHeight(4)
+	require.Nil(t, err)
+	assert.Equal(t, types.HashBlock(a4), types.HashBlock(tip))
+}
+
+func TestReorgBelowFinalized(t *testing.T) {
+	chain := NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+	chain.SetEngine(NewProofOfWorkEngine(0, time.Second))
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+
+	// with the easiest target every block meets it
+	parent := genesis
+	for i := 0; i < defaultConfirmations+1; i++ {
+		b := blockOn(parent)
+		b.Header.Timestamp = parent.Header.Timestamp + 1
+		types.SignBlock(crypto.GeneratePrivateKey(), b)
+		require.Nil(t, chain.AddBlock(b))
+		parent = b
+	}
+	assert.Equal(t, 1, chain.FinalizedHeight())
+
+	fork := blockOn(genesis)
+	fork.Header.Timestamp = genesis.Header.Timestamp + 1
+	types.SignBlock(crypto.GeneratePrivateKey(), fork)
+	assert.ErrorContains(t, chain.AddBlock(fork), "final")
+}
+
+func TestGetBalance(t *testing.T) {
+	var (
+		chain     = NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+		privKey   = GenesisKey()
+		owner     = privKey.Public().Address()
+		recipient = crypto.GeneratePrivateKey().Public().Address()
+	)
+	balance, err := chain.GetBalance(owner)
+	require.Nil(t, err)
+	assert.Equal(t, int64(1000), balance)
+
+	tx := genesisSpend(t, chain)
+	tx.Outputs = []*proto.TxOutput{
+		{Amount: 100, Address: recipient.Bytes()},
+		{Amount: 900, Address: owner.Bytes()},
+	}
+	tx.Inputs[0].Signature = types.SignTransaction(privKey, tx).Bytes()
+	b := randomBlock(t, chain)
+	b.Transactions = []*proto.Transaction{tx}
+	types.SignBlock(privKey, b)
+	require.Nil(t, chain.AddBlock(b))
+
+	balance, err = chain.GetBalance(owner)
+	require.Nil(t, err)
+	assert.Equal(t, int64(900), balance)
+	utxos, err := chain.ListUnspent(recipient)
+	require.Nil(t, err)
+	require.Len(t, utxos, 1)
+	assert.Equal(t, hex.EncodeToString(types.HashTransaction(tx)), utxos[0].Hash)
+	assert.Equal(t, 0, utxos[0].OutIndex)
+	assert.Equal(t, int64(100), utxos[0].Amount)
+
+	// the genesis output is spent now
+	assert.ErrorContains(t, chain.ValidateTransaction(genesisSpend(t, chain)), "already spent")
+
+	unknown, err := chain.ListUnspent(crypto.GeneratePrivateKey().Public().Address())
+	require.Nil(t, err)
+	assert.Empty(t, unknown)
+}
+
+func TestValidateTransactionAmounts(t *testing.T) {
+	var (
+		chain   = NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+		privKey = GenesisKey()
+	)
+	sign := func(tx *proto.Transaction) *proto.Transaction {
+		for _, input := range tx.Inputs {
+			input.Signature = nil
+		}
+		sig := types.SignTransaction(privKey, tx).Bytes()
+		for _, input := range tx.Inputs {
+			input.Signature = sig
+		}
+		return tx
+	}
+
+	// spending the same output twice must not double its amount
+	tx := genesisSpend(t, chain)
+	tx.Inputs = append(tx.Inputs, &proto.TxInput{
+		PrevTxHash: tx.Inputs[0].PrevTxHash,
+		PublicKey:  tx.Inputs[0].PublicKey,
+	})
+	tx.Outputs[0].Amount = 2000
+	assert.ErrorContains(t, chain.ValidateTransaction(sign(tx)), "twice")
+
+	for _, amount := range []int64{0, -1} {
+		tx = genesisSpend(t, chain)
+		tx.Outputs = append(tx.Outputs, &proto.TxOutput{
+			Amount:  amount,
+			Address: tx.Outputs[0].Address,
+		})
+		assert.ErrorContains(t, chain.ValidateTransaction(sign(tx)), "non positive")
+	}
+
+	tx = genesisSpend(t, chain)
+	tx.Outputs = []*proto.TxOutput{
+		{Amount: math.MaxInt64, Address: tx.Outputs[0].Address},
+		{Amount: math.MaxInt64, Address: tx.Outputs[0].Address},
+	}
+	assert.ErrorContains(t, chain.ValidateTransaction(sign(tx)), "overflow")
+
+	assert.Nil(t, chain.ValidateTransaction(genesisSpend(t, chain)))
+}
+
+func TestGetBalanceIgnoresForeignLockScripts(t *testing.T) {
+	var (
+		chain    = NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+		privKey  = GenesisKey()
+		victim   = crypto.GeneratePrivateKey().Public().Address()
+		attacker = crypto.GeneratePrivateKey().Public().Address()
+	)
+	// names the victim but only the attacker can spend it
+	tx := genesisSpend(t, chain)
+	tx.Outputs = []*proto.TxOutput{
+		{Amount: 600, Address: victim.Bytes(), LockScript: script.PayToAddress(attacker.Bytes())},
+		{Amount: 400, Address: victim.Bytes(), LockScript: script.PayToAddress(victim.Bytes())},
+	}
+	tx.Inputs[0].Signature = types.SignTransaction(privKey, tx).Bytes()
+	b := randomBlock(t, chain)
+	b.Transactions = []*proto.Transaction{tx}
+	types.SignBlock(privKey, b)
+	require.Nil(t, chain.AddBlock(b))
+
+	balance, err := chain.GetBalance(victim)
+	require.Nil(t, err)
+	assert.Equal(t, int64(400), balance)
+	balance, err = chain.GetBalance(attacker)
+	require.Nil(t, err)
+	assert.Zero(t, balance)
+}
+
+func TestDoubleSpendInLaterBlock(t *testing.T) {
+	var (
+		chain   = NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+		privKey = GenesisKey()
+	)
+	b := randomBlock(t, chain)
+	b.Transactions = []*proto.Transaction{genesisSpend(t, chain)}
+	types.SignBlock(privKey, b)
+	require.Nil(t, chain.AddBlock(b))
+
+	// another spend of the same output, to another recipient
+	again := randomBlock(t, chain)
+	again.Transactions = []*proto.Transaction{genesisSpend(t, chain)}
+	types.SignBlock(privKey, again)
+	assert.ErrorContains(t, chain.AddBlock(again), "already spent")
+	assert.Equal(t, 1, chain.Height())
+}
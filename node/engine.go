@@ -0,0 +1,102 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/64bitAryan/blocker/crypto"
+	"github.com/64bitAryan/blocker/proto"
+	"github.com/64bitAryan/blocker/types"
+)
+
+// HeaderReader looks up the headers of the stored blocks of every branch,
+// engines walk the ancestors of a block with it.
+type HeaderReader interface {
+	GetHeader(hash []byte) (*proto.Header, error)
+}
+
+// Engine is the consensus of a chain: it schedules block production, seals
+// and verifies blocks, weighs branches and decides when blocks are final.
+// The validator set passed in is the one of the chain, nil without one.
+type Engine interface {
+	// Schedule returns when pubKey may produce the block on top of parent,
+	// false while it may not produce blocks at all.
+	Schedule(vs *ValidatorSet, parent *proto.Header, pubKey *crypto.PublicKeys, now time.Time) (time.Time, bool)
+	// Earliest returns the first time pubKey may produce the block on top
+	// of parent, for blocks that are due before their schedule.
+	Earliest(vs *ValidatorSet, parent *proto.Header, pubKey *crypto.PublicKeys, now time.Time) (time.Time, bool)
+	// Seal completes the header of b and signs it with privKey, giving up
+	// when ctx is done.
+	Seal(ctx context.Context, chain HeaderReader, b *proto.Block, parent *proto.Header, privKey *crypto.PrivateKeys) error
+	// VerifySeal checks the consensus rules of b on top of parent.
+	VerifySeal(chain HeaderReader, vs *ValidatorSet, b *proto.Block, parent *proto.Header, now time.Time) error
+	// Work is the weight a block adds to its branch, fork choice follows
+	// the branch with the most work.
+	Work(h *proto.Header) *big.Int
+	// FinalityDepth is the number of confirmations after which a block is
+	// final, 0 when the validators finalize blocks with precommit votes.
+	FinalityDepth() int
+}
+
+// AuthorityEngine is proof of authority, the validators take turns in the
+// slots of the validator set and finalize blocks by voting. A single
+// validator is a set of one. Without a set every producer seals a block
+// every blockTime and any signed block is accepted.
+type AuthorityEngine struct {
+	blockTime time.Duration
+}
+
+func NewAuthorityEngine(blockTime time.Duration) *AuthorityEngine {
+	return &AuthorityEngine{
+		blockTime: blockTime,
+	}
+}
+
+func (e *AuthorityEngine) Schedule(vs *ValidatorSet, parent *proto.Header, pubKey *crypto.PublicKeys, now time.Time) (time.Time, bool) {
+	if vs == nil {
+		vs = NewValidatorSet(e.blockTime, pubKey)
+	}
+	if !vs.Contains(pubKey) {
+		return time.Time{}, false
+	}
+	slot := max(vs.Slot(now.UnixNano()), vs.Slot(parent.Timestamp)) + 1
+	for !bytes.Equal(vs.Proposer(slot).Bytes(), pubKey.Bytes()) {
+		slot++
+	}
+	return vs.SlotStart(slot), true
+}
+
+// Earliest is now in our own slot when it follows the slot of parent, any
+// time without a set.
+func (e *AuthorityEngine) Earliest(vs *ValidatorSet, parent *proto.Header, pubKey *crypto.PublicKeys, now time.Time) (time.Time, bool) {
+	if vs == nil {
+		return now, true
+	}
+	slot := vs.Slot(now.UnixNano())
+	if slot > vs.Slot(parent.Timestamp) && bytes.Equal(vs.Proposer(slot).Bytes(), pubKey.Bytes()) {
+		return now, true
+	}
+	return e.Schedule(vs, parent, pubKey, now)
+}
+
+func (e *AuthorityEngine) Seal(ctx context.Context, chain HeaderReader, b *proto.Block, parent *proto.Header, privKey *crypto.PrivateKeys) error {
+	types.SignBlock(privKey, b)
+	return nil
+}
+
+func (e *AuthorityEngine) VerifySeal(chain HeaderReader, vs *ValidatorSet, b *proto.Block, parent *proto.Header, now time.Time) error {
+	if vs == nil {
+		return nil
+	}
+	return vs.validateProposer(b, parent, now)
+}
+
+func (e *AuthorityEngine) Work(h *proto.Header) *big.Int {
+	return big.NewInt(1)
+}
+
+func (e *AuthorityEngine) FinalityDepth() int {
+	return 0
+}
@@ -0,0 +1,70 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/64bitAryan/blocker/crypto"
+	"github.com/64bitAryan/blocker/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthorityEngineSchedule(t *testing.T) {
+	var (
+		engine       = NewAuthorityEngine(time.Second)
+		_, pubKeys   = validatorKeys(3)
+		vs           = NewValidatorSet(time.Second, pubKeys...)
+		now          = time.Unix(30, int64(time.Millisecond*500))
+		parent       = &proto.Header{Timestamp: time.Unix(29, 0).UnixNano()}
+		futureParent = &proto.Header{Timestamp: time.Unix(33, 0).UnixNano()}
+	)
+	// slot 31 belongs to the second validator
+	at, ok := engine.Schedule(vs, parent, pubKeys[1], now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Unix(31, 0), at)
+	at, ok = engine.Schedule(vs, parent, pubKeys[0], now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Unix(33, 0), at)
+	at, _ = engine.Schedule(vs, futureParent, pubKeys[1], now)
+	assert.Equal(t, time.Unix(34, 0), at)
+
+	_, ok = engine.Schedule(vs, parent, crypto.GeneratePrivateKey().Public(), now)
+	assert.False(t, ok)
+
+	// without a set every slot is ours
+	at, ok = engine.Schedule(nil, parent, crypto.GeneratePrivateKey().Public(), now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Unix(31, 0), at)
+}
+
+func TestAuthorityEngineWithoutSet(t *testing.T) {
+	chain := NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+	b := randomBlock(t, chain)
+	assert.Nil(t, NewAuthorityEngine(time.Second).VerifySeal(chain, nil, b, chain.headers.Get(0), time.Now()))
+	assert.Equal(t, 0, NewAuthorityEngine(time.Second).FinalityDepth())
+}
+
+func TestAuthorityEngineEarliest(t *testing.T) {
+	var (
+		engine     = NewAuthorityEngine(time.Second)
+		_, pubKeys = validatorKeys(3)
+		vs         = NewValidatorSet(time.Second, pubKeys...)
+		now        = time.Unix(30, int64(time.Millisecond*500))
+		parent     = &proto.Header{Timestamp: time.Unix(29, 0).UnixNano()}
+	)
+	// slot 30 belongs to the first validator
+	at, ok := engine.Earliest(vs, parent, pubKeys[0], now)
+	assert.True(t, ok)
+	assert.Equal(t, now, at)
+	at, _ = engine.Earliest(vs, parent, pubKeys[1], now)
+	assert.Equal(t, time.Unix(31, 0), at)
+
+	// not twice in a slot
+	parent = &proto.Header{Timestamp: time.Unix(30, 0).UnixNano()}
+	at, _ = engine.Earliest(vs, parent, pubKeys[0], now)
+	assert.Equal(t, time.Unix(33, 0), at)
+
+	at, ok = engine.Earliest(nil, parent, pubKeys[0], now)
+	assert.True(t, ok)
+	assert.Equal(t, now, at)
+}
@@ -0,0 +1,263 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/64bitAryan/blocker/crypto"
+	"github.com/64bitAryan/blocker/proto"
+	"github.com/64bitAryan/blocker/types"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// Validators signing two blocks at the same height are slashed: anyone may
+// submit both signed headers as evidence, a block includes it and the
+// validator is ejected from the set at the next epoch boundary. It cannot
+// be added again.
+
+// most evidence a block may hold
+const maxBlockEvidence = 10
+
+// errUnusableEvidence is returned for evidence honest nodes relay too, but
+// which can not slash here: the validator is slashed already or we miss the
+// blocks the evidence builds on.
+var errUnusableEvidence = errors.New("unusable evidence")
+
+// evidencePool holds the evidence not on the chain yet.
+type evidencePool struct {
+	lock     sync.Mutex
+	evidence map[string]*proto.Evidence
+}
+
+func newEvidencePool() *evidencePool {
+	return &evidencePool{
+		evidence: make(map[string]*proto.Evidence),
+	}
+}
+
+func (pool *evidencePool) add(ev *proto.Evidence) bool {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	key := hex.EncodeToString(types.EvidenceHash(ev))
+	if _, ok := pool.evidence[key]; ok {
+		return false
+	}
+	pool.evidence[key] = ev
+	return true
+}
+
+func (pool *evidencePool) remove(ev *proto.Evidence) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	delete(pool.evidence, hex.EncodeToString(types.EvidenceHash(ev)))
+}
+
+func (pool *evidencePool) list() []*proto.Evidence {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	evidence := make([]*proto.Evidence, 0, len(pool.evidence))
+	for _, ev := range pool.evidence {
+		evidence = append(evidence, ev)
+	}
+	return evidence
+}
+
+// ValidateEvidence checks that ev can slash a validator of the chain.
+func (c *Chain) ValidateEvidence(ev *proto.Evidence) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.validateEvidence(ev)
+}
+
+func (c *Chain) validateEvidence(ev *proto.Evidence) error {
+	if err := types.VerifyEvidence(ev); err != nil {
+		return err
+	}
+	var (
+		height = int64(ev.First.Header.Height)
+		key    = ev.First.PublicKey
+	)
+	if height < 1 {
+		return fmt.Errorf("evidence of height %d", height)
+	}
+	if height > int64(c.Height()+1) {
+		return fmt.Errorf("evidence of height %d beyond the tip: %w", height, errUnusableEvidence)
+	}
+	// headers signed on another chain, a testnet sharing the keys say, must
+	// not slash here
+	for _, sh := range []*proto.SignedHeader{ev.First, ev.Second} {
+		parent, err := c.GetBlockByHash(sh.Header.PrevHash)
+		if err != nil || int64(parent.Header.Height) != height-1 {
+			return fmt.Errorf("evidence of height %d does not build on our chain: %w", height, errUnusableEvidence)
+		}
+	}
+	vs := c.validatorSetAt(height)
+	if vs == nil || !vs.Contains(crypto.PublicKeyFromBytes(key)) {
+		return fmt.Errorf("evidence of %x who was not a validator at height %d", key, height)
+	}
+	if c.governance.slashed[hex.EncodeToString(key)] {
+		return fmt.Errorf("validator %x is slashed already: %w", key, errUnusableEvidence)
+	}
+	// the chain would halt without validators, so the last one stays
+	if c.slashesEveryValidator([]string{hex.EncodeToString(key)}) {
+		return fmt.Errorf("cannot slash %x, the last validator: %w", key, errUnusableEvidence)
+	}
+	return nil
+}
+
+// slashesEveryValidator reports whether removing the validators of keys
+// empties the set of the next epoch.
+func (c *Chain) slashesEveryValidator(keys []string) bool {
+	if c.validators == nil {
+		return false
+	}
+	vs := c.pendingValidators()
+	left := vs.Len()
+	for _, key := range keys {
+		pubKey, err := hex.DecodeString(key)
+		if err == nil && vs.Contains(crypto.PublicKeyFromBytes(pubKey)) {
+			left--
+		}
+	}
+	return left < 1
+}
+
+// validateBlockEvidence checks the evidence of a block, every validator can
+// be slashed once.
+func (c *Chain) validateBlockEvidence(b *proto.Block) error {
+	if len(b.Evidence) > maxBlockEvidence {
+		return fmt.Errorf("block with %d evidence, at most %d", len(b.Evidence), maxBlockEvidence)
+	}
+	slashed := make(map[string]bool)
+	for _, ev := range b.Evidence {
+		if err := c.validateEvidence(ev); err != nil {
+			return err
+		}
+		key := hex.EncodeToString(ev.First.PublicKey)
+		if slashed[key] {
+			return fmt.Errorf("validator %s slashed twice in a block", key)
+		}
+		slashed[key] = true
+	}
+	keys := make([]string, 0, len(slashed))
+	for key := range slashed {
+		keys = append(keys, key)
+	}
+	if c.slashesEveryValidator(keys) {
+		return fmt.Errorf("block slashes every validator left")
+	}
+	return nil
+}
+
+// slash schedules the removal of the signer of the validated ev.
+func (c *Chain) slash(ev *proto.Evidence) {
+	c.governance.slashed[hex.EncodeToString(ev.First.PublicKey)] = true
+	c.governance.scheduled = append(c.governance.scheduled, &proto.ValidatorChange{
+		Action:    proto.ValidatorChange_REMOVE,
+		PublicKey: ev.First.PublicKey,
+	})
+}
+
+func evidenceKey(ev *proto.Evidence) string {
+	return "evidence:" + hex.EncodeToString(types.EvidenceHash(ev))
+}
+
+func (n *Node) HandleEvidence(ctx context.Context, ev *proto.Evidence) (*proto.Ack, error) {
+	if n.isBanned(ctx, "") {
+		return nil, fmt.Errorf("banned")
+	}
+	p, err := n.senderPeer(ctx, ev.ListenAddr)
+	if err != nil {
+		return nil, err
+	}
+	if p != nil {
+		p.known.Add(evidenceKey(ev))
+	}
+	ev.ListenAddr = ""
+	err = n.addEvidence(ev)
+	if errors.Is(err, errUnusableEvidence) {
+		return nil, err
+	}
+	if err != nil {
+		n.senderMisbehaving(ctx, p, penaltyInvalidEvidence, "invalid evidence")
+		return nil, err
+	}
+	return &proto.Ack{}, nil
+}
+
+// addEvidence pools valid evidence and relays it when it is new.
+func (n *Node) addEvidence(ev *proto.Evidence) error {
+	if err := n.chain.ValidateEvidence(ev); err != nil {
+		return err
+	}
+	if !n.evidence.add(ev) {
+		return nil
+	}
+	n.logger.Warnw("validator equivocated", "we", n.ListenAddr, "validator", hex.EncodeToString(ev.First.PublicKey), "height", ev.First.Header.Height)
+	n.relayEvidence(ev)
+	return nil
+}
+
+// relayEvidence queues ev for every peer that does not know it yet.
+func (n *Node) relayEvidence(ev *proto.Evidence) {
+	var (
+		key     = evidenceKey(ev)
+		relayed = pb.Clone(ev).(*proto.Evidence)
+	)
+	relayed.ListenAddr = n.ListenAddr
+
+	n.peerLock.RLock()
+	defer n.peerLock.RUnlock()
+	for addr, p := range n.peers {
+		if !p.known.Add(key) {
+			continue
+		}
+		if !p.enqueue(relayed) {
+			n.logger.Warnw("send queue full, dropping message", "we", n.ListenAddr, "remote", addr)
+		}
+	}
+}
+
+// checkEquivocation pools evidence when the signer of b signed another
+// block of our chain at its height.
+func (n *Node) checkEquivocation(b *proto.Block) {
+	if !types.VerifyBlock(b) {
+		return
+	}
+	other, err := n.chain.GetBlockByHeight(int(b.Header.Height))
+	if err != nil || !bytes.Equal(other.PublicKey, b.PublicKey) {
+		return
+	}
+	if bytes.Equal(types.HashBlock(other), types.HashBlock(b)) {
+		return
+	}
+	if err := n.addEvidence(types.NewEvidence(other, b)); err != nil {
+		n.logger.Debugw("could not add evidence", "we", n.ListenAddr, "err", err)
+	}
+}
+
+// blockEvidence returns the pooled evidence that is still valid, dropping
+// the rest.
+func (n *Node) blockEvidence() []*proto.Evidence {
+	var (
+		evidence = []*proto.Evidence{}
+		slashed  = make(map[string]bool)
+	)
+	for _, ev := range n.evidence.list() {
+		key := string(ev.First.PublicKey)
+		if err := n.chain.ValidateEvidence(ev); err != nil || slashed[key] {
+			n.evidence.remove(ev)
+			continue
+		}
+		if len(evidence) == maxBlockEvidence {
+			break
+		}
+		slashed[key] = true
+		evidence = append(evidence, ev)
+	}
+	return evidence
+}
@@ -0,0 +1,154 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/64bitAryan/blocker/crypto"
+	"github.com/64bitAryan/blocker/proto"
+	"github.com/64bitAryan/blocker/types"
+	"github.com/64bitAryan/blocker/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	grpcpeer "google.golang.org/grpc/peer"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// equivocate returns another block of the signer of b at its height.
+func equivocate(privKey *crypto.PrivateKeys, b *proto.Block) *proto.Block {
+	other := pb.Clone(b).(*proto.Block)
+	other.Header.Timestamp++
+	types.SignBlock(privKey, other)
+	return other
+}
+
+func TestSlashEquivocation(t *testing.T) {
+	gc, _ := newGovernanceChain(t, 4)
+	gc.addBlock()
+	b, err := gc.chain.GetBlockByHeight(1)
+	require.Nil(t, err)
+	var (
+		privKey = gc.keys[string(b.PublicKey)]
+		ev      = types.NewEvidence(b, equivocate(privKey, b))
+	)
+	require.Nil(t, gc.chain.ValidateEvidence(ev))
+
+	// evidence of someone outside the set is no use
+	outsider := crypto.GeneratePrivateKey()
+	forged := slotBlock(t, gc.chain, gc.slot, outsider)
+	assert.ErrorContains(t, gc.chain.ValidateEvidence(types.NewEvidence(forged, equivocate(outsider, forged))), "not a validator")
+
+	// headers of another chain do not slash here
+	other := pb.Clone(b).(*proto.Block)
+	other.Header.PrevHash = util.RandomHash()
+	types.SignBlock(privKey, other)
+	assert.ErrorContains(t, gc.chain.ValidateEvidence(types.NewEvidence(other, equivocate(privKey, other))), "does not build on our chain")
+
+	// a block may slash a validator once
+	gc.slot++
+	proposer := gc.keys[string(gc.chain.ValidatorSet().Proposer(gc.slot).Bytes())]
+	withEvidence := slotBlock(t, gc.chain, gc.slot, proposer)
+	withEvidence.Evidence = []*proto.Evidence{ev, ev}
+	types.SignBlock(proposer, withEvidence)
+	assert.ErrorContains(t, gc.chain.AddBlock(withEvidence), "twice")
+
+	withEvidence.Evidence = []*proto.Evidence{ev}
+	types.SignBlock(proposer, withEvidence)
+	require.Nil(t, gc.chain.AddBlock(withEvidence))
+	assert.ErrorContains(t, gc.chain.ValidateEvidence(ev), "slashed already")
+
+	// ejected at the end of the epoch, for good
+	slashed := crypto.PublicKeyFromBytes(b.PublicKey)
+	assert.True(t, gc.chain.ValidatorSet().Contains(slashed))
+	gc.addBlocksUntil(epochLength)
+	assert.False(t, gc.chain.ValidatorSet().Contains(slashed))
+	assert.Equal(t, 3, gc.chain.ValidatorSet().Len())
+
+	readd := types.NewValidatorProposal(proposer, &proto.ValidatorChange{
+		Action:    proto.ValidatorChange_ADD,
+		PublicKey: b.PublicKey,
+	})
+	assert.ErrorContains(t, gc.chain.ValidateTransaction(readd), "slashed")
+}
+
+func TestSlashLastValidator(t *testing.T) {
+	gc, _ := newGovernanceChain(t, 2)
+	gc.addBlock()
+	gc.addBlock()
+	evidence := []*proto.Evidence{}
+	for height := 1; height <= 2; height++ {
+		b, err := gc.chain.GetBlockByHeight(height)
+		require.Nil(t, err)
+		ev := types.NewEvidence(b, equivocate(gc.keys[string(b.PublicKey)], b))
+		require.Nil(t, gc.chain.ValidateEvidence(ev))
+		evidence = append(evidence, ev)
+	}
+	require.NotEqual(t, evidence[0].First.PublicKey, evidence[1].First.PublicKey)
+
+	// both can't go at once
+	gc.slot++
+	proposer := gc.keys[string(gc.chain.ValidatorSet().Proposer(gc.slot).Bytes())]
+	b := slotBlock(t, gc.chain, gc.slot, proposer)
+	b.Evidence = evidence
+	types.SignBlock(proposer, b)
+	assert.ErrorContains(t, gc.chain.AddBlock(b), "every validator")
+
+	// once one is slashed the other one is the last validator
+	b.Evidence = evidence[:1]
+	types.SignBlock(proposer, b)
+	require.Nil(t, gc.chain.AddBlock(b))
+	err := gc.chain.ValidateEvidence(evidence[1])
+	assert.ErrorContains(t, err, "last validator")
+	assert.ErrorIs(t, err, errUnusableEvidence)
+
+	gc.addBlocksUntil(epochLength)
+	assert.Equal(t, 1, gc.chain.ValidatorSet().Len())
+	assert.ErrorContains(t, gc.chain.ValidateEvidence(evidence[1]), "last validator")
+}
+
+func TestCheckEquivocation(t *testing.T) {
+	var (
+		privKeys, pubKeys = validatorKeys(3)
+		n                 = NewNode(ServerConfig{
+			PrivateKey: privKeys[0],
+			Validators: pubKeys,
+			Params:     ChainParams{BlockTime: time.Second},
+		})
+		vs   = n.chain.ValidatorSet()
+		slot = vs.Slot(time.Now().UnixNano()) - 3
+	)
+	for !bytes.Equal(vs.Proposer(slot).Bytes(), pubKeys[1].Bytes()) {
+		slot--
+	}
+	b := slotBlock(t, n.chain, slot, privKeys[1])
+	require.Nil(t, n.processBlock(b))
+	assert.Empty(t, n.blockEvidence())
+
+	require.Nil(t, n.processBlock(equivocate(privKeys[1], b)))
+	evidence := n.blockEvidence()
+	require.Len(t, evidence, 1)
+	assert.Equal(t, b.PublicKey, evidence[0].First.PublicKey)
+
+	// the next block slashes the validator
+	next := slotBlock(t, n.chain, slot+2, privKeys[0])
+	next.Evidence = evidence
+	types.SignBlock(privKeys[0], next)
+	require.Nil(t, n.processBlock(next))
+	assert.Empty(t, n.evidence.list())
+	assert.ErrorContains(t, n.chain.ValidateEvidence(evidence[0]), "slashed")
+
+	// relaying evidence that is stale by now costs nothing
+	client := grpcpeer.NewContext(context.Background(), &grpcpeer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 50000},
+	})
+	_, err := n.HandleEvidence(client, evidence[0])
+	assert.ErrorContains(t, err, "slashed already")
+	assert.Zero(t, n.bans.Score("10.0.0.1", time.Now()))
+
+	_, err = n.HandleEvidence(client, &proto.Evidence{})
+	assert.NotNil(t, err)
+	assert.Equal(t, penaltyInvalidEvidence, n.bans.Score("10.0.0.1", time.Now()))
+}
@@ -0,0 +1,342 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/64bitAryan/blocker/crypto"
+	"github.com/64bitAryan/blocker/proto"
+	"github.com/64bitAryan/blocker/types"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// Finality works in Tendermint style rounds on top of the block producer.
+// The block of the slot proposer is the proposal of the round, every
+// validator prevotes for the first block it adds at a height and
+// precommits once more than two thirds prevoted for it. A block with
+// precommits of more than two thirds of the validators is final.
+
+// votes are kept for heights at most this far beyond our tip, the block
+// may still be on its way
+const maxVoteLead = 1
+
+var errConflictingVote = errors.New("conflicting vote")
+
+type heightVotes struct {
+	// by type and hex public key of the voter
+	votes map[proto.VoteType]map[string]*proto.Vote
+	// the types we voted already
+	cast map[proto.VoteType]bool
+}
+
+// voteBook collects the votes of the heights that are not final yet.
+type voteBook struct {
+	lock    sync.Mutex
+	heights map[int64]*heightVotes
+}
+
+func newVoteBook() *voteBook {
+	return &voteBook{
+		heights: make(map[int64]*heightVotes),
+	}
+}
+
+func (book *voteBook) height(height int64) *heightVotes {
+	hv, ok := book.heights[height]
+	if !ok {
+		hv = &heightVotes{
+			votes: make(map[proto.VoteType]map[string]*proto.Vote),
+			cast:  make(map[proto.VoteType]bool),
+		}
+		book.heights[height] = hv
+	}
+	return hv
+}
+
+// add reports whether v is new, a validator voting for another block at
+// the same height is an error.
+func (book *voteBook) add(v *proto.Vote) (bool, error) {
+	book.lock.Lock()
+	defer book.lock.Unlock()
+	hv := book.height(v.Height)
+	votes, ok := hv.votes[v.Type]
+	if !ok {
+		votes = make(map[string]*proto.Vote)
+		hv.votes[v.Type] = votes
+	}
+	voter := hex.EncodeToString(v.PublicKey)
+	if prev, ok := votes[voter]; ok {
+		if bytes.Equal(prev.BlockHash, v.BlockHash) {
+			return false, nil
+		}
+		return false, fmt.Errorf("%w of %s at height %d", errConflictingVote, voter, v.Height)
+	}
+	votes[voter] = v
+	return true, nil
+}
+
+// markCast reports whether we did not vote typ at height yet.
+func (book *voteBook) markCast(height int64, typ proto.VoteType) bool {
+	book.lock.Lock()
+	defer book.lock.Unlock()
+	hv := book.height(height)
+	if hv.cast[typ] {
+		return false
+	}
+	hv.cast[typ] = true
+	return true
+}
+
+func (book *voteBook) votesFor(height int64, typ proto.VoteType, hash []byte) []*proto.Vote {
+	book.lock.Lock()
+	defer book.lock.Unlock()
+	hv, ok := book.heights[height]
+	if !ok {
+		return nil
+	}
+	votes := []*proto.Vote{}
+	for _, v := range hv.votes[typ] {
+		if bytes.Equal(v.BlockHash, hash) {
+			votes = append(votes, v)
+		}
+	}
+	return votes
+}
+
+// prune drops the votes of the heights up to height.
+func (book *voteBook) prune(height int64) {
+	book.lock.Lock()
+	defer book.lock.Unlock()
+	for h := range book.heights {
+		if h <= height {
+			delete(book.heights, h)
+		}
+	}
+}
+
+// countVoters returns the number of validators of the set among the voters.
+func (vs *ValidatorSet) countVoters(votes []*proto.Vote) int {
+	voters := make(map[string]bool)
+	for _, v := range votes {
+		if vs.Contains(crypto.PublicKeyFromBytes(v.PublicKey)) {
+			voters[string(v.PublicKey)] = true
+		}
+	}
+	return len(voters)
+}
+
+// verifyCommit checks that commit holds valid precommits of more than two
+// thirds of the set.
+func (vs *ValidatorSet) verifyCommit(commit *proto.Commit) error {
+	for _, v := range commit.Precommits {
+		if v.Type != proto.VoteType_PRECOMMIT || v.Height != commit.Height || !bytes.Equal(v.BlockHash, commit.BlockHash) {
+			return fmt.Errorf("commit holds a vote for something else")
+		}
+		if !types.VerifyVote(v) {
+			return fmt.Errorf("invalid precommit signature")
+		}
+	}
+	if !vs.quorum(vs.countVoters(commit.Precommits)) {
+		return fmt.Errorf("commit without a quorum of precommits")
+	}
+	return nil
+}
+
+// Finalize stores the commit certificate of a block on the chain, neither
+// the block nor its ancestors can be reverted after.
+func (c *Chain) Finalize(commit *proto.Commit) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.engine.FinalityDepth() > 0 {
+		return fmt.Errorf("blocks are final after %d confirmations", c.engine.FinalityDepth())
+	}
+	if int(commit.Height) <= c.finalized {
+		return fmt.Errorf("height %d is final already", commit.Height)
+	}
+	b, err := c.GetBlockByHeight(int(commit.Height))
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(types.HashBlock(b), commit.BlockHash) {
+		return fmt.Errorf("commit for block %x which is not on the chain", commit.BlockHash)
+	}
+	vs := c.validatorSetAt(commit.Height)
+	if vs == nil {
+		return fmt.Errorf("no validator set at height %d", commit.Height)
+	}
+	if err := vs.verifyCommit(commit); err != nil {
+		return err
+	}
+	if err := c.blockstore.PutCommit(commit); err != nil {
+		return err
+	}
+	c.finalized = int(commit.Height)
+	return nil
+}
+
+func (c *Chain) FinalizedHeight() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.finalized
+}
+
+// GetCommit returns the commit certificate of the block at height, only
+// blocks that were finalized directly have one.
+func (c *Chain) GetCommit(height int) (*proto.Commit, error) {
+	if height < 0 || height > c.Height() {
+		return nil, fmt.Errorf("no block at height %d - height (%d)", height, c.Height())
+	}
+	b, err := c.GetBlockByHeight(height)
+	if err != nil {
+		return nil, err
+	}
+	return c.blockstore.GetCommit(hex.EncodeToString(types.HashBlock(b)))
+}
+
+func voteKey(v *proto.Vote) string {
+	return "vote:" + hex.EncodeToString(types.VoteHash(v))
+}
+
+func (n *Node) HandleVote(ctx context.Context, v *proto.Vote) (*proto.Ack, error) {
+	p, err := n.senderPeer(ctx, v.ListenAddr)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, fmt.Errorf("vote from unknown peer %s", v.ListenAddr)
+	}
+	addr := v.ListenAddr
+	v.ListenAddr = ""
+	p.known.Add(voteKey(v))
+
+	added, err := n.addVote(v)
+	if errors.Is(err, errConflictingVote) {
+		n.logger.Warnw("conflicting vote", "we", n.ListenAddr, "remote", addr, "err", err)
+		return &proto.Ack{}, nil
+	}
+	if err != nil {
+		n.senderMisbehaving(ctx, p, penaltyInvalidVote, err.Error())
+		return nil, err
+	}
+	if added {
+		n.relayVote(v)
+		n.tally(v.Height)
+	}
+	return &proto.Ack{}, nil
+}
+
+func (n *Node) GetCommit(ctx context.Context, req *proto.CommitRequest) (*proto.Commit, error) {
+	return n.chain.GetCommit(int(req.Height))
+}
+
+// addVote reports whether v is a new vote of a validator. Votes for final
+// heights or too far ahead are ignored.
+func (n *Node) addVote(v *proto.Vote) (bool, error) {
+	if v.Height <= int64(n.chain.FinalizedHeight()) || v.Height > int64(n.chain.Height()+maxVoteLead) {
+		return false, nil
+	}
+	vs := n.chain.ValidatorSetAt(v.Height)
+	if vs == nil {
+		return false, fmt.Errorf("vote without a validator set")
+	}
+	if !types.VerifyVote(v) {
+		return false, fmt.Errorf("invalid vote signature")
+	}
+	if !vs.Contains(crypto.PublicKeyFromBytes(v.PublicKey)) {
+		return false, fmt.Errorf("vote of %x who is not a validator", v.PublicKey)
+	}
+	return n.votes.add(v)
+}
+
+// relayVote queues v for every peer that does not know it yet.
+func (n *Node) relayVote(v *proto.Vote) {
+	var (
+		key     = voteKey(v)
+		relayed = pb.Clone(v).(*proto.Vote)
+	)
+	relayed.ListenAddr = n.ListenAddr
+
+	n.peerLock.RLock()
+	defer n.peerLock.RUnlock()
+	for addr, p := range n.peers {
+		if !p.known.Add(key) {
+			continue
+		}
+		if !p.enqueue(relayed) {
+			n.logger.Warnw("send queue full, dropping message", "we", n.ListenAddr, "remote", addr)
+		}
+	}
+}
+
+// onBlock starts the voting on a block we added to the chain.
+func (n *Node) onBlock(b *proto.Block) {
+	if n.chain.Engine().FinalityDepth() > 0 {
+		return
+	}
+	n.castVote(proto.VoteType_PREVOTE, b)
+	n.tally(int64(b.Header.Height))
+}
+
+// castVote signs and sends our vote of typ for b, once per height.
+func (n *Node) castVote(typ proto.VoteType, b *proto.Block) {
+	height := int64(b.Header.Height)
+	vs := n.chain.ValidatorSetAt(height)
+	if n.PrivateKey == nil || vs == nil || !vs.Contains(n.PrivateKey.Public()) {
+		return
+	}
+	if !n.votes.markCast(height, typ) {
+		return
+	}
+	v := &proto.Vote{
+		Type:      typ,
+		Height:    height,
+		Round:     vs.Slot(b.Header.Timestamp),
+		BlockHash: types.HashBlock(b),
+	}
+	types.SignVote(n.PrivateKey, v)
+	if _, err := n.votes.add(v); err != nil {
+		n.logger.Errorw("could not add our vote", "err", err)
+		return
+	}
+	n.relayVote(v)
+}
+
+// tally precommits our block at height once it has a quorum of prevotes
+// and finalizes it once it has a quorum of precommits.
+func (n *Node) tally(height int64) {
+	if height <= int64(n.chain.FinalizedHeight()) || height > int64(n.chain.Height()) {
+		return
+	}
+	vs := n.chain.ValidatorSetAt(height)
+	if vs == nil {
+		return
+	}
+	b, err := n.chain.GetBlockByHeight(int(height))
+	if err != nil {
+		return
+	}
+	hash := types.HashBlock(b)
+	if vs.quorum(vs.countVoters(n.votes.votesFor(height, proto.VoteType_PREVOTE, hash))) {
+		n.castVote(proto.VoteType_PRECOMMIT, b)
+	}
+	precommits := n.votes.votesFor(height, proto.VoteType_PRECOMMIT, hash)
+	if !vs.quorum(vs.countVoters(precommits)) {
+		return
+	}
+	commit := &proto.Commit{
+		Height:     height,
+		BlockHash:  hash,
+		Precommits: precommits,
+	}
+	if err := n.chain.Finalize(commit); err != nil {
+		n.logger.Debugw("could not finalize", "we", n.ListenAddr, "height", height, "err", err)
+		return
+	}
+	n.votes.prune(height)
+	n.logger.Debugw("block final", "we", n.ListenAddr, "height", height, "hash", hex.EncodeToString(hash))
+}
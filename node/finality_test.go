@@ -0,0 +1,194 @@
+package node
+
+import (
+	"context"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/64bitAryan/blocker/crypto"
+	"github.com/64bitAryan/blocker/proto"
+	"github.com/64bitAryan/blocker/types"
+	"github.com/64bitAryan/blocker/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	grpcpeer "google.golang.org/grpc/peer"
+)
+
+func signedVote(privKey *crypto.PrivateKeys, typ proto.VoteType, b *proto.Block) *proto.Vote {
+	v := &proto.Vote{
+		Type:      typ,
+		Height:    int64(b.Header.Height),
+		BlockHash: types.HashBlock(b),
+	}
+	types.SignVote(privKey, v)
+	return v
+}
+
+func TestVoteBook(t *testing.T) {
+	var (
+		book    = newVoteBook()
+		privKey = crypto.GeneratePrivateKey()
+		v       = &proto.Vote{Height: 1, BlockHash: util.RandomHash()}
+	)
+	types.SignVote(privKey, v)
+
+	added, err := book.add(v)
+	require.Nil(t, err)
+	assert.True(t, added)
+	added, err = book.add(v)
+	require.Nil(t, err)
+	assert.False(t, added)
+
+	other := &proto.Vote{Height: 1, BlockHash: util.RandomHash()}
+	types.SignVote(privKey, other)
+	_, err = book.add(other)
+	assert.ErrorIs(t, err, errConflictingVote)
+
+	assert.Len(t, book.votesFor(1, proto.VoteType_PREVOTE, v.BlockHash), 1)
+	assert.Empty(t, book.votesFor(1, proto.VoteType_PRECOMMIT, v.BlockHash))
+
+	assert.True(t, book.markCast(1, proto.VoteType_PREVOTE))
+	assert.False(t, book.markCast(1, proto.VoteType_PREVOTE))
+	book.prune(1)
+	assert.Empty(t, book.votesFor(1, proto.VoteType_PREVOTE, v.BlockHash))
+}
+
+func TestFinalizeSingleValidator(t *testing.T) {
+	privKey := crypto.GeneratePrivateKey()
+	n := NewNode(ServerConfig{
+		PrivateKey: privKey,
+		Validators: []*crypto.PublicKeys{privKey.Public()},
+		Params:     ChainParams{BlockTime: time.Millisecond},
+	})
+	require.Nil(t, n.produceBlock(nil))
+	assert.Equal(t, 1, n.chain.FinalizedHeight())
+
+	commit, err := n.GetCommit(context.Background(), &proto.CommitRequest{Height: 1})
+	require.Nil(t, err)
+	assert.Len(t, commit.Precommits, 1)
+	assert.Nil(t, n.chain.ValidatorSet().verifyCommit(commit))
+
+	for _, height := range []int64{-1, 2, math.MaxInt32} {
+		_, err = n.GetCommit(context.Background(), &proto.CommitRequest{Height: height})
+		assert.NotNil(t, err)
+	}
+}
+
+func TestFinalizeQuorum(t *testing.T) {
+	var (
+		privKeys, pubKeys = validatorKeys(3)
+		n                 = NewNode(ServerConfig{
+			PrivateKey: privKeys[0],
+			Validators: pubKeys,
+			Params:     ChainParams{BlockTime: time.Second},
+		})
+		relay = &fakeNodeClient{alive: true}
+		ctx   = context.Background()
+		now   = n.chain.ValidatorSet().Slot(time.Now().UnixNano())
+	)
+	addFakePeer(n, "10.0.0.1:3000", relay)
+	vote := func(v *proto.Vote) {
+		v.ListenAddr = "10.0.0.1:3000"
+		_, err := n.HandleVote(ctx, v)
+		require.Nil(t, err)
+	}
+
+	b := slotBlock(t, n.chain, now, privKeys[now%3])
+	require.Nil(t, n.processBlock(b))
+	// one more prevote is no quorum yet
+	vote(signedVote(privKeys[1], proto.VoteType_PREVOTE, b))
+	assert.Empty(t, n.votes.votesFor(1, proto.VoteType_PRECOMMIT, types.HashBlock(b)))
+	vote(signedVote(privKeys[2], proto.VoteType_PREVOTE, b))
+	assert.Len(t, n.votes.votesFor(1, proto.VoteType_PRECOMMIT, types.HashBlock(b)), 1)
+
+	vote(signedVote(privKeys[1], proto.VoteType_PRECOMMIT, b))
+	assert.Equal(t, 0, n.chain.FinalizedHeight())
+	vote(signedVote(privKeys[2], proto.VoteType_PRECOMMIT, b))
+	assert.Equal(t, 1, n.chain.FinalizedHeight())
+	commit, err := n.chain.GetCommit(1)
+	require.Nil(t, err)
+	assert.Len(t, commit.Precommits, 3)
+
+	// the block can not be replaced
+	fork := &proto.Block{
+		Header: &proto.Header{
+			Version:   1,
+			Height:    1,
+			PrevHash:  b.Header.PrevHash,
+			Timestamp: n.chain.ValidatorSet().SlotStart(now + 1).UnixNano(),
+		},
+	}
+	types.SignBlock(privKeys[(now+1)%3], fork)
+	assert.ErrorContains(t, n.chain.ValidateBlock(fork), "final")
+	assert.Eventually(t, func() bool {
+		return relay.received.Load() > 0
+	}, time.Second, time.Millisecond*10)
+}
+
+func TestInvalidVote(t *testing.T) {
+	var (
+		privKeys, pubKeys = validatorKeys(2)
+		n                 = NewNode(ServerConfig{Validators: pubKeys, Params: ChainParams{BlockTime: time.Second}})
+		now               = n.chain.ValidatorSet().Slot(time.Now().UnixNano())
+	)
+	addFakePeer(n, "10.0.0.1:3000", &fakeNodeClient{alive: true})
+	b := slotBlock(t, n.chain, now, privKeys[now%2])
+	require.Nil(t, n.processBlock(b))
+
+	// the vote claims to come from the peer, the connection decides
+	spoofer := grpcpeer.NewContext(context.Background(), &grpcpeer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.2"), Port: 50000},
+	})
+	v := signedVote(crypto.GeneratePrivateKey(), proto.VoteType_PREVOTE, b)
+	v.ListenAddr = "10.0.0.1:3000"
+	_, err := n.HandleVote(spoofer, v)
+	assert.ErrorContains(t, err, "connection from 10.0.0.2")
+	assert.Zero(t, n.bans.Score("10.0.0.1:3000", time.Now()))
+
+	// and pays without TLS
+	sender := grpcpeer.NewContext(context.Background(), &grpcpeer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 50000},
+	})
+	v.ListenAddr = "10.0.0.1:3000"
+	_, err = n.HandleVote(sender, v)
+	assert.NotNil(t, err)
+	assert.Equal(t, penaltyInvalidVote, n.bans.Score("10.0.0.1", time.Now()))
+	assert.Zero(t, n.bans.Score("10.0.0.1:3000", time.Now()))
+
+	v = signedVote(privKeys[0], proto.VoteType_PREVOTE, b)
+	_, err = n.HandleVote(context.Background(), v)
+	assert.NotNil(t, err)
+}
+
+func TestFinalizeRejectsCommitWithoutQuorum(t *testing.T) {
+	var (
+		privKeys, pubKeys = validatorKeys(3)
+		chain             = NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+		vs                = NewValidatorSet(time.Second, pubKeys...)
+		now               = vs.Slot(time.Now().UnixNano())
+	)
+	chain.SetValidatorSet(vs)
+	b := slotBlock(t, chain, now, privKeys[now%3])
+	require.Nil(t, chain.AddBlock(b))
+
+	commit := &proto.Commit{
+		Height:    1,
+		BlockHash: types.HashBlock(b),
+		Precommits: []*proto.Vote{
+			signedVote(privKeys[0], proto.VoteType_PRECOMMIT, b),
+			signedVote(privKeys[1], proto.VoteType_PRECOMMIT, b),
+		},
+	}
+	assert.NotNil(t, chain.Finalize(commit))
+	commit.Precommits = append(commit.Precommits, signedVote(privKeys[1], proto.VoteType_PRECOMMIT, b))
+	assert.NotNil(t, chain.Finalize(commit))
+	commit.Precommits = append(commit.Precommits, signedVote(privKeys[2], proto.VoteType_PREVOTE, b))
+	assert.NotNil(t, chain.Finalize(commit))
+
+	commit.Precommits[3] = signedVote(privKeys[2], proto.VoteType_PRECOMMIT, b)
+	require.Nil(t, chain.Finalize(commit))
+	assert.Equal(t, 1, chain.FinalizedHeight())
+	assert.NotNil(t, chain.Finalize(commit))
+}
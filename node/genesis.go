@@ -0,0 +1,213 @@
+package node
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/64bitAryan/blocker/crypto"
+	"github.com/64bitAryan/blocker/proto"
+	"github.com/64bitAryan/blocker/types"
+	"gopkg.in/yaml.v3"
+)
+
+// the chain ID of the development genesis
+const devChainID = "blocker-dev"
+
+// Genesis describes the first block of a chain, every node of the chain
+// has to load the same one. It is read from JSON or YAML files.
+type Genesis struct {
+	ChainID   string    `json:"chainId" yaml:"chainId"`
+	Timestamp time.Time `json:"timestamp" yaml:"timestamp"`
+	// Alloc are the outputs of the genesis transaction
+	Alloc  []Allocation  `json:"alloc" yaml:"alloc"`
+	Params GenesisParams `json:"params" yaml:"params"`
+	// Validators are hex public keys, any key may produce blocks without
+	Validators []string `json:"validators" yaml:"validators"`
+}
+
+type Allocation struct {
+	// Address is hex encoded
+	Address string `json:"address" yaml:"address"`
+	Amount  int64  `json:"amount" yaml:"amount"`
+}
+
+// GenesisParams are the ChainParams of a genesis file, the unset ones take
+// their default.
+type GenesisParams struct {
+	// BlockTime is a duration like "5s"
+	BlockTime     string `json:"blockTime,omitempty" yaml:"blockTime,omitempty"`
+	MaxBlockBytes int    `json:"maxBlockBytes,omitempty" yaml:"maxBlockBytes,omitempty"`
+	MaxBlockTxs   int    `json:"maxBlockTxs,omitempty" yaml:"maxBlockTxs,omitempty"`
+	MaxTxBytes    int    `json:"maxTxBytes,omitempty" yaml:"maxTxBytes,omitempty"`
+}
+
+// DevGenesis returns the genesis of a development chain, allocating 1000
+// to the GenesisKey.
+func DevGenesis(params ChainParams, validators ...*crypto.PublicKeys) *Genesis {
+	g := &Genesis{
+		ChainID: devChainID,
+		Alloc: []Allocation{{
+			Address: GenesisKey().Public().Address().String(),
+			Amount:  1000,
+		}},
+		Params: GenesisParams{
+			MaxBlockBytes: params.MaxBlockBytes,
+			MaxBlockTxs:   params.MaxBlockTxs,
+			MaxTxBytes:    params.MaxTxBytes,
+		},
+	}
+	if params.BlockTime != 0 {
+		g.Params.BlockTime = params.BlockTime.String()
+	}
+	for _, validator := range validators {
+		g.Validators = append(g.Validators, hex.EncodeToString(validator.Bytes()))
+	}
+	return g
+}
+
+// LoadGenesis reads a genesis file, YAML unless its extension is .json.
+func LoadGenesis(path string) (*Genesis, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	g := &Genesis{}
+	if filepath.Ext(path) == ".json" {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(g)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(g)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse genesis %s: %w", path, err)
+	}
+	if _, err := g.Block(); err != nil {
+		return nil, fmt.Errorf("invalid genesis %s: %w", path, err)
+	}
+	return g, nil
+}
+
+// ChainParams returns the parameters of the chain.
+func (g *Genesis) ChainParams() (ChainParams, error) {
+	params := ChainParams{
+		MaxBlockBytes: g.Params.MaxBlockBytes,
+		MaxBlockTxs:   g.Params.MaxBlockTxs,
+		MaxTxBytes:    g.Params.MaxTxBytes,
+	}
+	if len(g.Params.BlockTime) > 0 {
+		blockTime, err := time.ParseDuration(g.Params.BlockTime)
+		if err != nil {
+			return params, fmt.Errorf("invalid block time: %w", err)
+		}
+		params.BlockTime = blockTime
+	}
+	params = params.withDefaults()
+	if params.BlockTime < 0 || params.MaxBlockBytes < 0 || params.MaxBlockTxs < 0 || params.MaxTxBytes < 0 {
+		return params, fmt.Errorf("negative chain parameter")
+	}
+	// blocks travel in messages next to other items, keep room for them
+	if params.MaxBlockBytes > maxMessageSize/2 {
+		return params, fmt.Errorf("max block bytes above %d", maxMessageSize/2)
+	}
+	return params, nil
+}
+
+func (g *Genesis) validators() ([]*crypto.PublicKeys, error) {
+	var (
+		validators = []*crypto.PublicKeys{}
+		seen       = make(map[string]bool)
+	)
+	for _, s := range g.Validators {
+		b, err := hex.DecodeString(s)
+		if err != nil || len(b) != crypto.PubKeyLen {
+			return nil, fmt.Errorf("invalid validator public key %q", s)
+		}
+		if seen[string(b)] {
+			return nil, fmt.Errorf("validator %s listed twice", s)
+		}
+		seen[string(b)] = true
+		validators = append(validators, crypto.PublicKeyFromBytes(b))
+	}
+	return validators, nil
+}
+
+func (g *Genesis) outputs() ([]*proto.TxOutput, error) {
+	var (
+		outputs = []*proto.TxOutput{}
+		seen    = make(map[string]bool)
+		total   = int64(0)
+	)
+	for _, alloc := range g.Alloc {
+		b, err := hex.DecodeString(alloc.Address)
+		if err != nil || len(b) != crypto.AddressLen {
+			return nil, fmt.Errorf("invalid allocation address %q", alloc.Address)
+		}
+		if seen[string(b)] {
+			return nil, fmt.Errorf("address %s allocated twice", alloc.Address)
+		}
+		seen[string(b)] = true
+		if alloc.Amount <= 0 {
+			return nil, fmt.Errorf("allocation of %d to %s is not positive", alloc.Amount, alloc.Address)
+		}
+		if alloc.Amount > math.MaxInt64-total {
+			return nil, fmt.Errorf("allocations overflow")
+		}
+		total += alloc.Amount
+		outputs = append(outputs, &proto.TxOutput{
+			Amount:  alloc.Amount,
+			Address: b,
+		})
+	}
+	return outputs, nil
+}
+
+// Block builds the genesis block. It is signed by the GenesisKey, so that
+// every node builds the same block, the signature vouches for nothing.
+func (g *Genesis) Block() (*proto.Block, error) {
+	if len(g.ChainID) == 0 {
+		return nil, fmt.Errorf("genesis without a chain ID")
+	}
+	params, err := g.ChainParams()
+	if err != nil {
+		return nil, err
+	}
+	validators, err := g.validators()
+	if err != nil {
+		return nil, err
+	}
+	outputs, err := g.outputs()
+	if err != nil {
+		return nil, err
+	}
+
+	header := &proto.Header{
+		Version: 1,
+		Params:  params.toProto(),
+		ChainID: g.ChainID,
+	}
+	if !g.Timestamp.IsZero() {
+		header.Timestamp = g.Timestamp.UnixNano()
+	}
+	for _, validator := range validators {
+		header.Validators = append(header.Validators, validator.Bytes())
+	}
+	block := &proto.Block{Header: header}
+	if len(outputs) > 0 {
+		block.Transactions = []*proto.Transaction{{
+			Version: 1,
+			Inputs:  []*proto.TxInput{},
+			Outputs: outputs,
+		}}
+	}
+	types.SignBlock(GenesisKey(), block)
+	return block, nil
+}
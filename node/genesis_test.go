@@ -0,0 +1,118 @@
+package node
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/64bitAryan/blocker/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeGenesis(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.Nil(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadGenesis(t *testing.T) {
+	var (
+		alice     = crypto.GeneratePrivateKey().Public()
+		bob       = crypto.GeneratePrivateKey().Public()
+		validator = crypto.GeneratePrivateKey().Public()
+	)
+	yamlPath := writeGenesis(t, "genesis.yaml", fmt.Sprintf(`chainId: testnet-1
+timestamp: 2024-06-01T00:00:00Z
+alloc:
+  - address: %s
+    amount: 500
+  - address: %s
+    amount: 250
+params:
+  blockTime: 2s
+  maxBlockTxs: 100
+validators:
+  - %s
+`, alice.Address(), bob.Address(), hex.EncodeToString(validator.Bytes())))
+	jsonPath := writeGenesis(t, "genesis.json", fmt.Sprintf(`{
+  "chainId": "testnet-1",
+  "timestamp": "2024-06-01T00:00:00Z",
+  "alloc": [
+    {"address": "%s", "amount": 500},
+    {"address": "%s", "amount": 250}
+  ],
+  "params": {"blockTime": "2s", "maxBlockTxs": 100},
+  "validators": ["%s"]
+}`, alice.Address(), bob.Address(), hex.EncodeToString(validator.Bytes())))
+
+	g, err := LoadGenesis(yamlPath)
+	require.Nil(t, err)
+	chain, err := NewChainFromGenesis(NewMemoryBlockStore(), NewMemoryTXStore(), g)
+	require.Nil(t, err)
+	assert.Equal(t, "testnet-1", chain.ChainID())
+	assert.Equal(t, time.Second*2, chain.Params().BlockTime)
+	assert.Equal(t, 100, chain.Params().MaxBlockTxs)
+	assert.Equal(t, DefaultChainParams().MaxTxBytes, chain.Params().MaxTxBytes)
+	assert.True(t, chain.ValidatorSet().Contains(validator))
+
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	outputs := genesis.Transactions[0].Outputs
+	require.Len(t, outputs, 2)
+	assert.Equal(t, alice.Address().Bytes(), outputs[0].Address)
+	assert.Equal(t, int64(250), outputs[1].Amount)
+
+	// the same genesis in JSON is the same chain
+	g, err = LoadGenesis(jsonPath)
+	require.Nil(t, err)
+	other, err := NewChainFromGenesis(NewMemoryBlockStore(), NewMemoryTXStore(), g)
+	require.Nil(t, err)
+	assert.Equal(t, chain.GenesisHash(), other.GenesisHash())
+}
+
+func TestLoadGenesisInvalid(t *testing.T) {
+	address := crypto.GeneratePrivateKey().Public().Address().String()
+	tests := map[string]string{
+		"unknown field":   "chainId: x\nfoo: 1\n",
+		"no chain ID":     "alloc: []\n",
+		"bad address":     "chainId: x\nalloc:\n  - address: abcd\n    amount: 1\n",
+		"twice":           fmt.Sprintf("chainId: x\nalloc:\n  - address: %s\n    amount: 1\n  - address: %s\n    amount: 1\n", address, address),
+		"negative amount": fmt.Sprintf("chainId: x\nalloc:\n  - address: %s\n    amount: -1\n", address),
+		"bad validator":   "chainId: x\nvalidators:\n  - 1234\n",
+		"bad block time":  "chainId: x\nparams:\n  blockTime: soon\n",
+		"negative param":  "chainId: x\nparams:\n  maxTxBytes: -1\n",
+		"huge blocks":     fmt.Sprintf("chainId: x\nparams:\n  maxBlockBytes: %d\n", maxMessageSize/2+1),
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadGenesis(writeGenesis(t, "genesis.yaml", content))
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestNodeInvalidGenesis(t *testing.T) {
+	n := NewNode(ServerConfig{Genesis: &Genesis{}})
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	assert.ErrorContains(t, n.Serve(context.Background(), ln, nil), "chain ID")
+}
+
+func TestChainParamsMaxBlockBytes(t *testing.T) {
+	g := &Genesis{ChainID: "x"}
+	g.Params.MaxBlockBytes = maxMessageSize / 2
+	params, err := g.ChainParams()
+	require.Nil(t, err)
+	assert.Equal(t, maxMessageSize/2, params.MaxBlockBytes)
+
+	// a block that may not fit in a message would stall the chain
+	g.Params.MaxBlockBytes++
+	_, err = g.ChainParams()
+	assert.ErrorContains(t, err, "max block bytes")
+}
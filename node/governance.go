@@ -0,0 +1,211 @@
+package node
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/64bitAryan/blocker/crypto"
+	"github.com/64bitAryan/blocker/proto"
+	"github.com/64bitAryan/blocker/types"
+)
+
+const (
+	// approved validator set changes take effect after the block whose
+	// height is a multiple of epochLength
+	epochLength = 10
+	// proposals without a supermajority after this many blocks are dropped
+	proposalTTL = 100
+)
+
+type proposal struct {
+	change *proto.ValidatorChange
+	// hex public keys of the validators that approved
+	approvals map[string]bool
+	height    int64
+}
+
+// governance tracks the validator set changes proposed on the chain. A
+// change is scheduled once more than two thirds of the current validators
+// approved it and applied at the next epoch boundary.
+type governance struct {
+	pending   map[string]*proposal
+	scheduled []*proto.ValidatorChange
+	// proposals that were scheduled, they cannot be proposed again
+	decided map[string]bool
+	// hex public keys of the validators ejected for equivocating
+	slashed map[string]bool
+	// hex hashes of the governance txs on the chain, replaying them after
+	// their proposal expired would revive it
+	included map[string]bool
+}
+
+func newGovernance() *governance {
+	return &governance{
+		pending:  make(map[string]*proposal),
+		decided:  make(map[string]bool),
+		slashed:  make(map[string]bool),
+		included: make(map[string]bool),
+	}
+}
+
+// validateGovernance checks a governance transaction against the current
+// validator set and proposals.
+func (c *Chain) validateGovernance(tx *proto.Transaction, batch *crypto.BatchVerifier) error {
+	gov := tx.Governance
+	if c.validators == nil {
+		return fmt.Errorf("governance tx without a validator set")
+	}
+	if len(tx.Inputs) > 0 || len(tx.Outputs) > 0 {
+		return fmt.Errorf("governance tx with inputs or outputs")
+	}
+	if len(gov.PublicKey) != crypto.PubKeyLen || len(gov.Signature) != crypto.SignatureLen {
+		return fmt.Errorf("governance tx is not signed")
+	}
+	if batch != nil {
+		batch.Add(crypto.PublicKeyFromBytes(gov.PublicKey), types.GovernanceHash(tx), crypto.SignatureFromBytes(gov.Signature))
+	} else if !types.VerifyGovernance(tx) {
+		return fmt.Errorf("invalid governance tx signature")
+	}
+	if !c.validators.Contains(crypto.PublicKeyFromBytes(gov.PublicKey)) {
+		return fmt.Errorf("governance tx signer is not a validator")
+	}
+	if hash := hex.EncodeToString(types.HashTransaction(tx)); c.governance.included[hash] {
+		return fmt.Errorf("governance tx %s is on the chain already", hash)
+	}
+
+	switch {
+	case gov.Change != nil && len(gov.Proposal) == 0:
+		return c.validateChange(gov.Change)
+	case gov.Change == nil && len(gov.Proposal) > 0:
+		p, ok := c.governance.pending[hex.EncodeToString(gov.Proposal)]
+		if !ok {
+			return fmt.Errorf("unknown proposal %x", gov.Proposal)
+		}
+		if p.approvals[hex.EncodeToString(gov.PublicKey)] {
+			return fmt.Errorf("proposal %x already approved by the signer", gov.Proposal)
+		}
+		return nil
+	default:
+		return fmt.Errorf("governance tx must either propose or approve")
+	}
+}
+
+func (c *Chain) validateChange(change *proto.ValidatorChange) error {
+	if len(change.PublicKey) != crypto.PubKeyLen {
+		return fmt.Errorf("invalid validator public key")
+	}
+	id := hex.EncodeToString(types.ProposalID(change))
+	if _, ok := c.governance.pending[id]; ok || c.governance.decided[id] {
+		return fmt.Errorf("proposal %s already made", id)
+	}
+	if change.Action == proto.ValidatorChange_ADD && c.governance.slashed[hex.EncodeToString(change.PublicKey)] {
+		return fmt.Errorf("validator %x was slashed", change.PublicKey)
+	}
+	return c.validators.checkChange(change)
+}
+
+// applyGovernance records the proposal or approval of tx, it has been
+// validated already.
+func (c *Chain) applyGovernance(tx *proto.Transaction, height int64) {
+	gov := tx.Governance
+	c.governance.included[hex.EncodeToString(types.HashTransaction(tx))] = true
+	id := hex.EncodeToString(gov.Proposal)
+	if gov.Change != nil {
+		id = hex.EncodeToString(types.ProposalID(gov.Change))
+		if _, ok := c.governance.pending[id]; !ok && !c.governance.decided[id] {
+			c.governance.pending[id] = &proposal{
+				change:    gov.Change,
+				approvals: make(map[string]bool),
+				height:    height,
+			}
+		}
+	}
+	p, ok := c.governance.pending[id]
+	if !ok {
+		return
+	}
+	p.approvals[hex.EncodeToString(gov.PublicKey)] = true
+	if !c.supermajority(p) {
+		return
+	}
+	delete(c.governance.pending, id)
+	c.governance.decided[id] = true
+	c.governance.scheduled = append(c.governance.scheduled, p.change)
+}
+
+// supermajority reports whether more than two thirds of the current
+// validators approved p.
+func (c *Chain) supermajority(p *proposal) bool {
+	approvals := 0
+	for _, validator := range c.validators.validators {
+		if p.approvals[hex.EncodeToString(validator.Bytes())] {
+			approvals++
+		}
+	}
+	return c.validators.quorum(approvals)
+}
+
+// endBlock drops expired proposals and applies the scheduled changes at
+// the end of an epoch.
+func (c *Chain) endBlock(height int64) {
+	for id, p := range c.governance.pending {
+		if height-p.height >= proposalTTL {
+			delete(c.governance.pending, id)
+		}
+	}
+	if c.validators == nil || height%epochLength != 0 || len(c.governance.scheduled) == 0 {
+		return
+	}
+	vs := c.pendingValidators()
+	c.governance.scheduled = nil
+	c.validators = vs
+	c.history = append(c.history, validatorEpoch{from: height + 1, set: vs})
+}
+
+// pendingValidators returns the set the scheduled changes lead to at the
+// end of the epoch.
+func (c *Chain) pendingValidators() *ValidatorSet {
+	vs := c.validators
+	for _, change := range c.governance.scheduled {
+		// an earlier change of the epoch may have made it pointless
+		if vs.checkChange(change) == nil {
+			vs = vs.withChange(change)
+		}
+	}
+	return vs
+}
+
+// checkChange reports why change cannot be applied to the set.
+func (vs *ValidatorSet) checkChange(change *proto.ValidatorChange) error {
+	pubKey := crypto.PublicKeyFromBytes(change.PublicKey)
+	switch change.Action {
+	case proto.ValidatorChange_ADD:
+		if vs.Contains(pubKey) {
+			return fmt.Errorf("%x is a validator already", change.PublicKey)
+		}
+	case proto.ValidatorChange_REMOVE:
+		if !vs.Contains(pubKey) {
+			return fmt.Errorf("%x is not a validator", change.PublicKey)
+		}
+		if vs.Len() == 1 {
+			return fmt.Errorf("cannot remove the last validator")
+		}
+	default:
+		return fmt.Errorf("unknown validator change %v", change.Action)
+	}
+	return nil
+}
+
+func (vs *ValidatorSet) withChange(change *proto.ValidatorChange) *ValidatorSet {
+	validators := []*crypto.PublicKeys{}
+	for _, validator := range vs.validators {
+		if !bytes.Equal(validator.Bytes(), change.PublicKey) {
+			validators = append(validators, validator)
+		}
+	}
+	if change.Action == proto.ValidatorChange_ADD {
+		validators = append(validators, crypto.PublicKeyFromBytes(change.PublicKey))
+	}
+	return NewValidatorSet(vs.slotDuration, validators...)
+}
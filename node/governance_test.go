@@ -0,0 +1,174 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/64bitAryan/blocker/crypto"
+	"github.com/64bitAryan/blocker/proto"
+	"github.com/64bitAryan/blocker/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type governanceChain struct {
+	t     *testing.T
+	chain *Chain
+	keys  map[string]*crypto.PrivateKeys
+	slot  int64
+}
+
+// newGovernanceChain returns a chain of n validators whose blocks start in
+// the past, so that a few epochs fit before the present slot.
+func newGovernanceChain(t *testing.T, n int) (*governanceChain, []*crypto.PrivateKeys) {
+	privKeys, pubKeys := validatorKeys(n)
+	vs := NewValidatorSet(time.Second, pubKeys...)
+	gc := &governanceChain{
+		t:     t,
+		chain: NewChain(NewMemoryBlockStore(), NewMemoryTXStore()),
+		keys:  make(map[string]*crypto.PrivateKeys),
+		slot:  vs.Slot(time.Now().UnixNano()) - 200,
+	}
+	gc.chain.SetValidatorSet(vs)
+	for _, privKey := range privKeys {
+		gc.addKey(privKey)
+	}
+	return gc, privKeys
+}
+
+func (gc *governanceChain) addKey(privKey *crypto.PrivateKeys) {
+	gc.keys[string(privKey.Public().Bytes())] = privKey
+}
+
+// addBlock adds the next block with txx, signed by the proposer of the
+// next slot.
+func (gc *governanceChain) addBlock(txx ...*proto.Transaction) {
+	gc.t.Helper()
+	gc.slot++
+	proposer := gc.chain.ValidatorSet().Proposer(gc.slot)
+	b := slotBlock(gc.t, gc.chain, gc.slot, gc.keys[string(proposer.Bytes())], txx...)
+	require.Nil(gc.t, gc.chain.AddBlock(b))
+}
+
+func (gc *governanceChain) addBlocksUntil(height int) {
+	gc.t.Helper()
+	for gc.chain.Height() < height {
+		gc.addBlock()
+	}
+}
+
+func TestGovernanceAddValidator(t *testing.T) {
+	var (
+		gc, privKeys = newGovernanceChain(t, 3)
+		chain        = gc.chain
+		newKey       = crypto.GeneratePrivateKey()
+		change       = &proto.ValidatorChange{
+			Action:    proto.ValidatorChange_ADD,
+			PublicKey: newKey.Public().Bytes(),
+		}
+		id = types.ProposalID(change)
+	)
+	gc.addKey(newKey)
+
+	assert.NotNil(t, chain.ValidateTransaction(types.NewValidatorApproval(privKeys[1], id)))
+	assert.NotNil(t, chain.ValidateTransaction(types.NewValidatorProposal(newKey, change)))
+
+	gc.addBlock(types.NewValidatorProposal(privKeys[0], change))
+	assert.NotNil(t, chain.ValidateTransaction(types.NewValidatorProposal(privKeys[1], change)))
+	assert.NotNil(t, chain.ValidateTransaction(types.NewValidatorApproval(privKeys[0], id)))
+
+	// two of three is not a supermajority
+	gc.addBlock(types.NewValidatorApproval(privKeys[1], id))
+	assert.Empty(t, chain.governance.scheduled)
+	gc.addBlock(types.NewValidatorApproval(privKeys[2], id))
+	assert.Len(t, chain.governance.scheduled, 1)
+
+	gc.addBlocksUntil(epochLength - 1)
+	assert.Equal(t, 3, chain.ValidatorSet().Len())
+	gc.addBlock()
+	vs := chain.ValidatorSet()
+	assert.Equal(t, 4, vs.Len())
+	assert.True(t, vs.Contains(newKey.Public()))
+	assert.Equal(t, time.Second, vs.SlotDuration())
+
+	// the new validator takes its turn
+	gc.addBlocksUntil(epochLength + 4)
+	signed := false
+	for height := epochLength + 1; height <= chain.Height(); height++ {
+		b, err := chain.GetBlockByHeight(height)
+		require.Nil(t, err)
+		signed = signed || string(b.PublicKey) == string(newKey.Public().Bytes())
+	}
+	assert.True(t, signed)
+}
+
+func TestGovernanceRemoveValidator(t *testing.T) {
+	var (
+		gc, privKeys = newGovernanceChain(t, 2)
+		chain        = gc.chain
+		change       = &proto.ValidatorChange{
+			Action:    proto.ValidatorChange_REMOVE,
+			PublicKey: privKeys[1].Public().Bytes(),
+		}
+	)
+	gc.addBlock(types.NewValidatorProposal(privKeys[0], change))
+	gc.addBlock(types.NewValidatorApproval(privKeys[1], types.ProposalID(change)))
+	// scheduled proposals cannot be made again
+	assert.NotNil(t, chain.ValidateTransaction(types.NewValidatorProposal(privKeys[0], change)))
+
+	gc.addBlocksUntil(epochLength)
+	vs := chain.ValidatorSet()
+	assert.Equal(t, 1, vs.Len())
+	assert.False(t, vs.Contains(privKeys[1].Public()))
+
+	// the last validator stays and removed ones cannot vote
+	last := &proto.ValidatorChange{
+		Action:    proto.ValidatorChange_REMOVE,
+		PublicKey: privKeys[0].Public().Bytes(),
+	}
+	assert.NotNil(t, chain.ValidateTransaction(types.NewValidatorProposal(privKeys[0], last)))
+	assert.NotNil(t, chain.ValidateTransaction(types.NewValidatorProposal(privKeys[1], &proto.ValidatorChange{
+		PublicKey: crypto.GeneratePrivateKey().Public().Bytes(),
+	})))
+}
+
+func TestGovernanceProposalExpires(t *testing.T) {
+	var (
+		gc, privKeys = newGovernanceChain(t, 3)
+		change       = &proto.ValidatorChange{
+			PublicKey: crypto.GeneratePrivateKey().Public().Bytes(),
+		}
+	)
+	gc.addBlock(types.NewValidatorProposal(privKeys[0], change))
+	assert.Len(t, gc.chain.governance.pending, 1)
+	gc.addBlocksUntil(proposalTTL + 1)
+	assert.Empty(t, gc.chain.governance.pending)
+	assert.NotNil(t, gc.chain.ValidateTransaction(types.NewValidatorApproval(privKeys[1], types.ProposalID(change))))
+}
+
+func TestGovernanceReplay(t *testing.T) {
+	var (
+		gc, privKeys = newGovernanceChain(t, 3)
+		change       = &proto.ValidatorChange{
+			PublicKey: crypto.GeneratePrivateKey().Public().Bytes(),
+		}
+		proposal = types.NewValidatorProposal(privKeys[0], change)
+		approval = types.NewValidatorApproval(privKeys[1], types.ProposalID(change))
+	)
+	gc.addBlock(proposal)
+	gc.addBlock(approval)
+	gc.addBlocksUntil(proposalTTL + 2)
+	require.Empty(t, gc.chain.governance.pending)
+
+	// the recorded txs don't bring the expired proposal back
+	assert.ErrorContains(t, gc.chain.ValidateTransaction(proposal), "on the chain already")
+	assert.ErrorContains(t, gc.chain.ValidateTransaction(approval), "on the chain already")
+}
+
+func TestGovernanceWithoutValidatorSet(t *testing.T) {
+	chain := NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+	tx := types.NewValidatorProposal(crypto.GeneratePrivateKey(), &proto.ValidatorChange{
+		PublicKey: crypto.GeneratePrivateKey().Public().Bytes(),
+	})
+	assert.NotNil(t, chain.ValidateTransaction(tx))
+}
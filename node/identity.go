@@ -0,0 +1,253 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/64bitAryan/blocker/crypto"
+	"github.com/64bitAryan/blocker/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	grpcpeer "google.golang.org/grpc/peer"
+	pb "google.golang.org/protobuf/proto"
+)
+
+const (
+	challengeLen     = 32
+	challengeTimeout = time.Second * 30
+	maxChallenges    = 1024
+)
+
+var handshakeDomain = []byte("blocker-handshake")
+
+// newChallenge hands out a nonce the remote node has to sign in its
+// handshake, so a recorded handshake can't be replayed.
+func (n *Node) newChallenge() ([]byte, error) {
+	nonce := make([]byte, challengeLen)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	n.challengeLock.Lock()
+	defer n.challengeLock.Unlock()
+	now := time.Now()
+	for key, expires := range n.challenges {
+		if now.After(expires) {
+			delete(n.challenges, key)
+		}
+	}
+	if len(n.challenges) >= maxChallenges {
+		return nil, fmt.Errorf("too many pending handshakes")
+	}
+	n.challenges[hex.EncodeToString(nonce)] = now.Add(challengeTimeout)
+	return nonce, nil
+}
+
+// useChallenge reports whether nonce was handed out by us and is still
+// pending, every challenge can only be used once.
+func (n *Node) useChallenge(nonce []byte) bool {
+	n.challengeLock.Lock()
+	defer n.challengeLock.Unlock()
+	key := hex.EncodeToString(nonce)
+	expires, ok := n.challenges[key]
+	delete(n.challenges, key)
+	return ok && time.Now().Before(expires)
+}
+
+func (n *Node) GetChallenge(ctx context.Context, _ *proto.Ack) (*proto.Challenge, error) {
+	nonce, err := n.newChallenge()
+	if err != nil {
+		return nil, err
+	}
+	return &proto.Challenge{
+		Nonce: nonce,
+	}, nil
+}
+
+// Identify answers the challenge with our signed version, without taking
+// us for a peer.
+func (n *Node) Identify(ctx context.Context, c *proto.Challenge) (*proto.Version, error) {
+	if len(c.Nonce) != challengeLen {
+		return nil, fmt.Errorf("invalid challenge")
+	}
+	return n.signedVersion(c.Nonce)
+}
+
+// identify checks that the node listening where p was dialed holds nodeKey,
+// otherwise a node could take the listen address of another one.
+func (n *Node) identify(p *peer, nodeKey []byte) error {
+	nonce := make([]byte, challengeLen)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(n.ctx, requestTimeout)
+	defer cancel()
+	var remote grpcpeer.Peer
+	v, err := p.Identify(ctx, &proto.Challenge{Nonce: nonce}, grpc.Peer(&remote))
+	if err != nil {
+		return err
+	}
+	if err := n.verifyVersion(v, nonce); err != nil {
+		return err
+	}
+	if !bytes.Equal(v.NodeKey, nodeKey) {
+		return fmt.Errorf("another node listens on %s", v.ListenAddr)
+	}
+	return checkTransportKey(&remote, nodeKey)
+}
+
+// signedVersion answers the challenge of the remote node and carries a new
+// nonce for the remote node to answer in return.
+func (n *Node) signedVersion(challenge []byte) (*proto.Version, error) {
+	nonce := make([]byte, challengeLen)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	v := n.getVersion()
+	v.Nonce = nonce
+	v.Challenge = challenge
+	v.Signature = n.NodeKey.Sign(versionDigest(v)).Bytes()
+	return v, nil
+}
+
+// verifyVersion checks that v is signed by its node key and answers
+// challenge.
+func (n *Node) verifyVersion(v *proto.Version, challenge []byte) error {
+	if len(v.NodeKey) != crypto.PubKeyLen || len(v.Signature) != crypto.SignatureLen {
+		return fmt.Errorf("peer %s did not identify itself", v.ListenAddr)
+	}
+	if bytes.Equal(v.NodeKey, n.NodeKey.Public().Bytes()) {
+		return fmt.Errorf("peer %s is ourself", v.ListenAddr)
+	}
+	if !bytes.Equal(v.Challenge, challenge) {
+		return fmt.Errorf("peer %s answered the wrong challenge", v.ListenAddr)
+	}
+	sig := crypto.SignatureFromBytes(v.Signature)
+	if !sig.Verify(crypto.PublicKeyFromBytes(v.NodeKey), versionDigest(v)) {
+		return fmt.Errorf("invalid handshake signature of peer %s", v.ListenAddr)
+	}
+	return nil
+}
+
+func versionDigest(v *proto.Version) []byte {
+	unsigned := pb.Clone(v).(*proto.Version)
+	unsigned.Signature = nil
+	b, err := pb.Marshal(unsigned)
+	if err != nil {
+		panic(err)
+	}
+	hash := sha256.New()
+	hash.Write(handshakeDomain)
+	hash.Write(b)
+	return hash.Sum(nil)
+}
+
+// checkTransportKey makes sure the TLS certificate of the connection
+// belongs to nodeKey, so the authenticated connection can't be handed
+// over to somebody else. Plain connections are not checked.
+func checkTransportKey(p *grpcpeer.Peer, nodeKey []byte) error {
+	if p == nil || p.AuthInfo == nil {
+		return nil
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil
+	}
+	certs := info.State.PeerCertificates
+	if len(certs) == 0 {
+		return fmt.Errorf("no certificate presented")
+	}
+	pubKey, err := crypto.VerifyCertificate(certs[0].Raw)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(pubKey.Bytes(), nodeKey) {
+		return fmt.Errorf("certificate does not match the node key")
+	}
+	return nil
+}
+
+// senderPeer returns the peer listening on addr, the address a message
+// claims to come from, or nil. Over TLS the certificate of the connection
+// has to belong to that peer, plain connections have to come from its host.
+func (n *Node) senderPeer(ctx context.Context, addr string) (*peer, error) {
+	p := n.getPeer(addr)
+	if p == nil {
+		return nil, nil
+	}
+	remote, ok := grpcpeer.FromContext(ctx)
+	if !ok {
+		return p, nil
+	}
+	if _, ok := remote.AuthInfo.(credentials.TLSInfo); !ok {
+		if host := remoteHost(ctx); !listensOn(ctx, addr, host) {
+			return nil, fmt.Errorf("message from %s over a connection from %s", addr, host)
+		}
+		return p, nil
+	}
+	if err := checkTransportKey(remote, p.version.NodeKey); err != nil {
+		return nil, fmt.Errorf("message from %s: %w", addr, err)
+	}
+	return p, nil
+}
+
+// listensOn reports whether addr is an address of host. Addresses without
+// a host are the ones of the same machine.
+func listensOn(ctx context.Context, addr string, host string) bool {
+	listenHost, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(listenHost)
+	if len(listenHost) == 0 || listenHost == "localhost" || (ip != nil && ip.IsUnspecified()) {
+		return isLoopback(host)
+	}
+	ips := []net.IP{ip}
+	if ip == nil {
+		ips, err = net.DefaultResolver.LookupIP(ctx, "ip", listenHost)
+		if err != nil {
+			return false
+		}
+	}
+	remoteIP := net.ParseIP(host)
+	for _, ip := range ips {
+		if ip.Equal(remoteIP) {
+			return true
+		}
+	}
+	return false
+}
+
+// fromPeer reports whether the TLS certificate of the connection of ctx
+// proves that p sent the message, plain connections prove nothing.
+func fromPeer(ctx context.Context, p *peer) bool {
+	remote, ok := grpcpeer.FromContext(ctx)
+	if !ok {
+		return false
+	}
+	if _, ok := remote.AuthInfo.(credentials.TLSInfo); !ok {
+		return false
+	}
+	return checkTransportKey(remote, p.version.NodeKey) == nil
+}
+
+// transportCredentials returns the server option and dial option of the
+// transport, TLS with the node key as certificate when enabled.
+func (n *Node) transportCredentials() (grpc.ServerOption, grpc.DialOption, error) {
+	if !n.TLS {
+		return grpc.EmptyServerOption{}, grpc.WithInsecure(), nil
+	}
+	cfg, err := crypto.NewTLSConfig(n.NodeKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	creds := credentials.NewTLS(cfg)
+	return grpc.Creds(creds), grpc.WithTransportCredentials(creds), nil
+}
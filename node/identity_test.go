@@ -0,0 +1,165 @@
+package node
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/64bitAryan/blocker/proto"
+	"github.com/64bitAryan/blocker/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	grpcpeer "google.golang.org/grpc/peer"
+)
+
+// signedVersionFor returns the version from would send to dial to.
+func signedVersionFor(t *testing.T, from *Node, to *Node) *proto.Version {
+	challenge, err := to.GetChallenge(context.Background(), &proto.Ack{})
+	require.Nil(t, err)
+	v, err := from.signedVersion(challenge.Nonce)
+	require.Nil(t, err)
+	return v
+}
+
+func freeAddr(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+func waitForListener(t *testing.T, addr string) {
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+		}
+		return err == nil
+	}, time.Second, time.Millisecond*10)
+}
+
+func TestHandshakeChallenge(t *testing.T) {
+	var (
+		n      = NewNode(ServerConfig{ListenAddr: ":3000"})
+		remote = NewNode(ServerConfig{ListenAddr: ":4000"})
+	)
+	dialNodes(n, remote)
+	v := signedVersionFor(t, remote, n)
+	resp, err := n.Handshake(context.Background(), v)
+	require.Nil(t, err)
+	assert.Nil(t, remote.verifyVersion(resp, v.Nonce))
+	assert.Equal(t, n.NodeKey.Public().Bytes(), resp.NodeKey)
+
+	// a recorded handshake can't be replayed
+	n.deletePeer(":4000")
+	_, err = n.Handshake(context.Background(), v)
+	assert.ErrorContains(t, err, "challenge")
+}
+
+func TestHandshakeRejectsForgedVersion(t *testing.T) {
+	var (
+		n      = NewNode(ServerConfig{ListenAddr: ":3000"})
+		remote = NewNode(ServerConfig{ListenAddr: ":4000"})
+	)
+
+	// claiming the key of another node without owning it
+	v := signedVersionFor(t, remote, n)
+	v.NodeKey = NewNode(ServerConfig{}).NodeKey.Public().Bytes()
+	_, err := n.Handshake(context.Background(), v)
+	assert.ErrorContains(t, err, "signature")
+
+	// connecting to ourself
+	v = signedVersionFor(t, n, n)
+	_, err = n.Handshake(context.Background(), v)
+	assert.ErrorContains(t, err, "ourself")
+
+	v = signedVersionFor(t, remote, n)
+	v.Signature = nil
+	_, err = n.Handshake(context.Background(), v)
+	assert.NotNil(t, err)
+	assert.Empty(t, n.getPeerList())
+}
+
+func TestHandshakeRejectsStolenListenAddr(t *testing.T) {
+	var (
+		n        = NewNode(ServerConfig{ListenAddr: ":3000"})
+		victim   = NewNode(ServerConfig{ListenAddr: ":4000"})
+		attacker = NewNode(ServerConfig{ListenAddr: ":4000"})
+	)
+	dialNodes(n, victim)
+
+	_, err := n.Handshake(context.Background(), signedVersionFor(t, attacker, n))
+	assert.ErrorContains(t, err, "another node")
+	assert.Empty(t, n.getPeerList())
+
+	// a node that doesn't answer on its address isn't taken either
+	attacker.ListenAddr = ":5000"
+	_, err = n.Handshake(context.Background(), signedVersionFor(t, attacker, n))
+	assert.NotNil(t, err)
+	assert.Empty(t, n.getPeerList())
+
+	_, err = n.Handshake(context.Background(), signedVersionFor(t, victim, n))
+	require.Nil(t, err)
+	assert.Equal(t, []string{":4000"}, n.getPeerList())
+}
+
+func TestDialTLS(t *testing.T) {
+	var (
+		addr   = freeAddr(t)
+		n      = NewNode(ServerConfig{TLS: true})
+		remote = NewNode(ServerConfig{TLS: true})
+		plain  = NewNode(ServerConfig{})
+	)
+	go n.Start(context.Background(), addr, []string{})
+	defer n.Stop()
+	waitForListener(t, addr)
+
+	// n dials back to check who listens on the address remote claims
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	remote.ListenAddr = ln.Addr().String()
+	go remote.Serve(context.Background(), ln, []string{})
+	defer remote.Stop()
+
+	p, v, err := remote.dialRemoteNode(addr)
+	require.Nil(t, err)
+	defer p.close()
+	assert.Equal(t, n.NodeKey.Public().Bytes(), v.NodeKey)
+
+	// nodes without TLS can't talk to a TLS node
+	_, _, err = plain.dialRemoteNode(addr)
+	assert.NotNil(t, err)
+}
+
+func TestSenderPeerPlainConnection(t *testing.T) {
+	n := NewNode(ServerConfig{ListenAddr: ":3000"})
+	addFakePeer(n, "10.0.0.1:3000", &fakeNodeClient{alive: true})
+	addFakePeer(n, ":4000", &fakeNodeClient{alive: true})
+	genesis, err := n.chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	item := &proto.InvItem{Type: proto.InvType_BLOCK, Hash: types.HashBlock(genesis)}
+	from := func(ip string) context.Context {
+		return grpcpeer.NewContext(context.Background(), &grpcpeer.Peer{
+			Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 50000},
+		})
+	}
+
+	// a client can't fill the inventory of a peer by claiming its address
+	inv := &proto.Inventory{Items: []*proto.InvItem{item}, ListenAddr: "10.0.0.1:3000"}
+	_, err = n.GetData(from("10.0.0.2"), inv)
+	assert.ErrorContains(t, err, "connection from 10.0.0.2")
+	assert.False(t, n.getPeer("10.0.0.1:3000").known.Has(invKey(item)))
+
+	_, err = n.GetData(from("10.0.0.1"), inv)
+	require.Nil(t, err)
+	assert.True(t, n.getPeer("10.0.0.1:3000").known.Has(invKey(item)))
+
+	// addresses without a host are on this machine
+	inv.ListenAddr = ":4000"
+	_, err = n.GetData(from("10.0.0.1"), inv)
+	assert.NotNil(t, err)
+	_, err = n.GetData(from("127.0.0.1"), inv)
+	require.Nil(t, err)
+	assert.True(t, n.getPeer(":4000").known.Has(invKey(item)))
+}
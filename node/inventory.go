@@ -0,0 +1,283 @@
+package node
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/64bitAryan/blocker/proto"
+	"github.com/64bitAryan/blocker/types"
+)
+
+// Transactions and blocks are relayed by announcing their hashes. A peer
+// only requests the items it does not know yet, and every peer remembers
+// which items the other side already knows so they are never sent back.
+
+const (
+	maxKnownInventory = 10_000
+	// most items a single Announce or GetData may carry
+	maxInventoryItems = 1000
+	// an item is requested again from another peer after this timeout
+	requestTimeout = time.Second * 10
+)
+
+type inventorySet struct {
+	lock  sync.Mutex
+	items map[string]struct{}
+	order []string
+}
+
+func newInventorySet() *inventorySet {
+	return &inventorySet{
+		items: make(map[string]struct{}),
+		order: []string{},
+	}
+}
+
+// Add reports false if the key was already in the set. The oldest keys are
+// evicted once the set holds maxKnownInventory keys.
+func (s *inventorySet) Add(key string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.items[key]; ok {
+		return false
+	}
+	s.items[key] = struct{}{}
+	s.order = append(s.order, key)
+	if len(s.order) > maxKnownInventory {
+		delete(s.items, s.order[0])
+		s.order = s.order[1:]
+	}
+	return true
+}
+
+func (s *inventorySet) Has(key string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, ok := s.items[key]
+	return ok
+}
+
+func invKey(item *proto.InvItem) string {
+	return fmt.Sprintf("%s-%s", item.Type, hex.EncodeToString(item.Hash))
+}
+
+func txInvItem(tx *proto.Transaction) *proto.InvItem {
+	return &proto.InvItem{
+		Type: proto.InvType_TX,
+		Hash: types.HashTransaction(tx),
+	}
+}
+
+func blockInvItem(b *proto.Block) *proto.InvItem {
+	return &proto.InvItem{
+		Type: proto.InvType_BLOCK,
+		Hash: types.HashBlock(b),
+	}
+}
+
+// announce queues the items for every peer that does not know them yet.
+func (n *Node) announce(items ...*proto.InvItem) {
+	n.peerLock.RLock()
+	defer n.peerLock.RUnlock()
+
+	for addr, p := range n.peers {
+		unknown := []*proto.InvItem{}
+		for _, item := range items {
+			if p.known.Add(invKey(item)) {
+				unknown = append(unknown, item)
+			}
+		}
+		if len(unknown) == 0 {
+			continue
+		}
+		inv := &proto.Inventory{
+			Items:      unknown,
+			ListenAddr: n.ListenAddr,
+		}
+		if !p.enqueue(inv) {
+			n.logger.Warnw("send queue full, dropping message", "we", n.ListenAddr, "remote", addr)
+		}
+	}
+}
+
+func (n *Node) Announce(ctx context.Context, inv *proto.Inventory) (*proto.Ack, error) {
+	p, err := n.senderPeer(ctx, inv.ListenAddr)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, fmt.Errorf("announce from unknown peer %s", inv.ListenAddr)
+	}
+	if len(inv.Items) > maxInventoryItems {
+		n.senderMisbehaving(ctx, p, penaltySpam, "oversized inventory")
+		return nil, fmt.Errorf("inventory of %d items exceeds limit (%d)", len(inv.Items), maxInventoryItems)
+	}
+
+	unknown := []*proto.InvItem{}
+	for _, item := range inv.Items {
+		p.known.Add(invKey(item))
+		if !n.hasItem(item) && n.markRequested(item) {
+			unknown = append(unknown, item)
+		}
+	}
+	if len(unknown) > 0 {
+		go n.fetch(p, unknown)
+	}
+	return &proto.Ack{}, nil
+}
+
+func (n *Node) GetData(ctx context.Context, inv *proto.Inventory) (*proto.Items, error) {
+	p, err := n.senderPeer(ctx, inv.ListenAddr)
+	if err != nil {
+		return nil, err
+	}
+	if len(inv.Items) > maxInventoryItems {
+		n.senderMisbehaving(ctx, p, penaltySpam, "oversized inventory")
+		return nil, fmt.Errorf("inventory of %d items exceeds limit (%d)", len(inv.Items), maxInventoryItems)
+	}
+	items := &proto.Items{}
+	for _, item := range inv.Items {
+		switch item.Type {
+		case proto.InvType_TX:
+			if tx, ok := n.mempool.Get(hex.EncodeToString(item.Hash)); ok {
+				items.Transactions = append(items.Transactions, tx)
+			} else if tx, err := n.chain.GetTransactionByHash(item.Hash); err == nil {
+				items.Transactions = append(items.Transactions, tx)
+			} else {
+				continue
+			}
+		case proto.InvType_BLOCK:
+			b, err := n.chain.GetBlockByHash(item.Hash)
+			if err != nil {
+				continue
+			}
+			items.Blocks = append(items.Blocks, b)
+		}
+		if p != nil {
+			p.known.Add(invKey(item))
+		}
+	}
+	return items, nil
+}
+
+func (n *Node) fetch(p *peer, items []*proto.InvItem) {
+	defer func() {
+		n.requestLock.Lock()
+		for _, item := range items {
+			delete(n.requested, invKey(item))
+		}
+		n.requestLock.Unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(n.ctx, requestTimeout)
+	defer cancel()
+	resp, err := p.GetData(ctx, &proto.Inventory{
+		Items:      items,
+		ListenAddr: n.ListenAddr,
+	})
+	if err != nil {
+		n.logger.Debugw("get data failed", "we", n.ListenAddr, "remote", p.version.ListenAddr, "err", err)
+		return
+	}
+
+	var (
+		addr      = p.version.ListenAddr
+		requested = make(map[string]bool, len(items))
+	)
+	for _, item := range items {
+		requested[invKey(item)] = true
+	}
+	for _, tx := range resp.Transactions {
+		if !requested[invKey(txInvItem(tx))] {
+			n.misbehaving(addr, penaltySpam, "unrequested transaction")
+			continue
+		}
+		if n.mempool.Has(tx) {
+			continue
+		}
+		err := n.chain.ValidateTransaction(tx)
+		if errors.Is(err, ErrSpentOutput) || errors.Is(err, ErrUnknownOutput) {
+			n.logger.Debugw("dropping stale tx", "we", n.ListenAddr, "remote", addr, "err", err)
+			continue
+		}
+		if err != nil {
+			n.misbehaving(addr, penaltyInvalidTx, err.Error())
+			continue
+		}
+		if n.mempool.Add(tx) {
+			n.announce(txInvItem(tx))
+		}
+	}
+	for _, b := range resp.Blocks {
+		if !requested[invKey(blockInvItem(b))] {
+			n.misbehaving(addr, penaltySpam, "unrequested block")
+			continue
+		}
+		err := n.processBlock(b)
+		if err == nil {
+			p.seenHeight(int64(b.Header.Height))
+		} else if errors.Is(err, ErrOrphanBlock) {
+			// honest nodes announce blocks we miss the parents of when we
+			// are behind
+			if err := n.syncBlocks(p, b); err != nil {
+				n.logger.Debugw("sync failed", "we", n.ListenAddr, "remote", addr, "err", err)
+			}
+		} else if err != nil {
+			n.misbehaving(addr, penaltyInvalidBlock, err.Error())
+		}
+	}
+}
+
+func (n *Node) processBlock(b *proto.Block) error {
+	hash := types.HashBlock(b)
+	if n.chain.HasBlock(hash) {
+		return nil
+	}
+	n.checkEquivocation(b)
+	if err := n.chain.AddBlock(b); err != nil {
+		n.logger.Debugw("rejected block", "we", n.ListenAddr, "hash", hex.EncodeToString(hash), "err", err)
+		return err
+	}
+	for _, tx := range b.Transactions {
+		n.mempool.Remove(hex.EncodeToString(types.HashTransaction(tx)))
+	}
+	for _, ev := range b.Evidence {
+		n.evidence.remove(ev)
+	}
+	n.announce(blockInvItem(b))
+	// blocks of another branch are not voted on
+	if n.chain.isCanonical(b.Header) {
+		n.onBlock(b)
+	}
+	return nil
+}
+
+func (n *Node) hasItem(item *proto.InvItem) bool {
+	switch item.Type {
+	case proto.InvType_TX:
+		if _, ok := n.mempool.Get(hex.EncodeToString(item.Hash)); ok {
+			return true
+		}
+		_, err := n.chain.GetTransactionByHash(item.Hash)
+		return err == nil
+	case proto.InvType_BLOCK:
+		return n.chain.HasBlock(item.Hash)
+	}
+	return true
+}
+
+// markRequested reports false if the item is already being requested.
+func (n *Node) markRequested(item *proto.InvItem) bool {
+	n.requestLock.Lock()
+	defer n.requestLock.Unlock()
+	key := invKey(item)
+	if t, ok := n.requested[key]; ok && time.Since(t) < requestTimeout {
+		return false
+	}
+	n.requested[key] = time.Now()
+	return true
+}
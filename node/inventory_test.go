@@ -0,0 +1,102 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/64bitAryan/blocker/crypto"
+	"github.com/64bitAryan/blocker/proto"
+	"github.com/64bitAryan/blocker/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInventorySetEviction(t *testing.T) {
+	set := newInventorySet()
+	assert.True(t, set.Add("a"))
+	assert.False(t, set.Add("a"))
+	for i := 0; i < maxKnownInventory; i++ {
+		set.Add(fmt.Sprintf("%d", i))
+	}
+	assert.False(t, set.Has("a"))
+	assert.True(t, set.Has(fmt.Sprintf("%d", maxKnownInventory-1)))
+	assert.Equal(t, maxKnownInventory, len(set.items))
+}
+
+func TestAnnounceFetchesUnknownTx(t *testing.T) {
+	var (
+		n      = NewNode(ServerConfig{ListenAddr: ":3000"})
+		tx     = &proto.Transaction{Version: 42}
+		item   = txInvItem(tx)
+		sender = &fakeNodeClient{alive: true, items: &proto.Items{Transactions: []*proto.Transaction{tx}}}
+		other  = &fakeNodeClient{alive: true}
+	)
+	addFakePeer(n, ":4000", sender)
+	addFakePeer(n, ":5000", other)
+	defer n.deletePeer(":4000")
+	defer n.deletePeer(":5000")
+
+	_, err := n.Announce(context.Background(), &proto.Inventory{
+		Items:      []*proto.InvItem{item},
+		ListenAddr: ":4000",
+	})
+	require.Nil(t, err)
+
+	require.Eventually(t, func() bool {
+		return other.received.Load() == 1
+	}, time.Second*5, time.Millisecond*10)
+	assert.True(t, n.mempool.Has(tx))
+	// never announced back to the node we got it from
+	assert.Zero(t, sender.received.Load())
+
+	// a second announcement of a known item is not fetched again
+	assert.True(t, n.hasItem(item))
+	_, err = n.Announce(context.Background(), &proto.Inventory{
+		Items:      []*proto.InvItem{item},
+		ListenAddr: ":5000",
+	})
+	require.Nil(t, err)
+
+	_, err = n.Announce(context.Background(), &proto.Inventory{ListenAddr: ":6000"})
+	assert.NotNil(t, err)
+}
+
+func TestGetData(t *testing.T) {
+	var (
+		n       = NewNode(ServerConfig{ListenAddr: ":3000"})
+		tx      = &proto.Transaction{Version: 42}
+		genesis = n.chain.headers.Get(0)
+	)
+	n.mempool.Add(tx)
+
+	items, err := n.GetData(context.Background(), &proto.Inventory{
+		Items: []*proto.InvItem{
+			txInvItem(tx),
+			{Type: proto.InvType_BLOCK, Hash: types.HashHeader(genesis)},
+			{Type: proto.InvType_BLOCK, Hash: make([]byte, 32)},
+		},
+	})
+	require.Nil(t, err)
+	assert.Equal(t, []*proto.Transaction{tx}, items.Transactions)
+	require.Len(t, items.Blocks, 1)
+	assert.Equal(t, genesis, items.Blocks[0].Header)
+}
+
+func TestProduceAndProcessBlock(t *testing.T) {
+	var (
+		validator = NewNode(ServerConfig{ListenAddr: ":3000", PrivateKey: crypto.GeneratePrivateKey()})
+		follower  = NewNode(ServerConfig{ListenAddr: ":4000"})
+		invalidTx = &proto.Transaction{Version: 1, Inputs: []*proto.TxInput{{PrevTxHash: make([]byte, 32)}}}
+	)
+	require.Nil(t, validator.produceBlock([]*proto.Transaction{invalidTx}))
+	require.Equal(t, 1, validator.chain.Height())
+
+	block, err := validator.chain.GetBlockByHeight(1)
+	require.Nil(t, err)
+	assert.Empty(t, block.Transactions)
+
+	follower.processBlock(block)
+	assert.Equal(t, 1, follower.chain.Height())
+}
@@ -3,8 +3,11 @@ package node
 import (
 	"context"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/64bitAryan/blocker/crypto"
@@ -12,19 +15,32 @@ import (
 	"github.com/64bitAryan/blocker/types"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/peer"
+	grpcpeer "google.golang.org/grpc/peer"
+	pb "google.golang.org/protobuf/proto"
 )
 
-const blockTime = time.Second * 5
+const (
+	defaultMaxInbound    = 16
+	defaultMaxOutbound   = 8
+	addrBookSaveInterval = time.Minute
+
+	// in-flight RPCs are cut off when they take longer on shutdown
+	shutdownTimeout = time.Second * 10
+	// queued broadcasts are dropped when they take longer on shutdown
+	drainTimeout = time.Second * 5
+)
 
 type Mempool struct {
 	lock sync.RWMutex
 	txx  map[string]*proto.Transaction
+	// closed and replaced whenever a transaction is added
+	added chan struct{}
 }
 
 func NewMempool() *Mempool {
 	return &Mempool{
-		txx: make(map[string]*proto.Transaction),
+		txx:   make(map[string]*proto.Transaction),
+		added: make(chan struct{}),
 	}
 }
 
@@ -44,9 +60,32 @@ func (pool *Mempool) Add(tx *proto.Transaction) bool {
 	defer pool.lock.Unlock()
 	hash := hex.EncodeToString(types.HashTransaction(tx))
 	pool.txx[hash] = tx
+	close(pool.added)
+	pool.added = make(chan struct{})
 	return true
 }
 
+// Added returns a channel that is closed when the next transaction is
+// added.
+func (pool *Mempool) Added() <-chan struct{} {
+	pool.lock.RLock()
+	defer pool.lock.RUnlock()
+	return pool.added
+}
+
+func (pool *Mempool) Get(hash string) (*proto.Transaction, bool) {
+	pool.lock.RLock()
+	defer pool.lock.RUnlock()
+	tx, ok := pool.txx[hash]
+	return tx, ok
+}
+
+func (pool *Mempool) Remove(hash string) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	delete(pool.txx, hash)
+}
+
 func (pool *Mempool) Clear() []*proto.Transaction {
 	pool.lock.Lock()
 	defer pool.lock.Unlock()
@@ -67,18 +106,88 @@ func (pool *Mempool) len() int {
 	return len(pool.txx)
 }
 
+// size returns the number of transactions and their marshalled size.
+func (pool *Mempool) size() (int, int) {
+	pool.lock.RLock()
+	defer pool.lock.RUnlock()
+	size := 0
+	for _, tx := range pool.txx {
+		size += pb.Size(tx)
+	}
+	return len(pool.txx), size
+}
+
 type ServerConfig struct {
-	Version    string
+	Version string
+	// NetworkID separates networks sharing the same software, peers on
+	// another network are rejected during the handshake
+	NetworkID  string
 	ListenAddr string
+	// NodeKey identifies the node to its peers, a new key is generated
+	// when it is not set
+	NodeKey *crypto.PrivateKeys
+	// TLS encrypts peer connections, authenticated by the node keys
+	TLS        bool
 	PrivateKey *crypto.PrivateKeys
+	// Validators are the only keys allowed to produce blocks, taking turns
+	// every Params.BlockTime. Any key may produce blocks when it is empty.
+	Validators []*crypto.PublicKeys
+	// Params of the genesis block, the unset ones take their default
+	Params ChainParams
+	// Genesis is the chain to join, a development chain of Params and
+	// Validators when nil. Its params and validators replace those above.
+	Genesis *Genesis
+	// Engine is the consensus, proof of authority over Validators when nil
+	Engine Engine
+	// Production decides when to seal blocks, in every slot by default
+	Production ProductionPolicy
+	// KeystoreFile is loaded as the validator key when PrivateKey is not set
+	KeystoreFile     string
+	KeystorePassword string
+	// AddrBookPath persists known peer addresses, empty keeps them in memory
+	AddrBookPath string
+	MaxInbound   int
+	MaxOutbound  int
+	// TrustLoopback never penalizes clients on the same machine, for local
+	// test networks
+	TrustLoopback bool
 }
 
 type Node struct {
 	ServerConfig
-	logger   *zap.SugaredLogger
-	peerLock sync.RWMutex
-	peers    map[proto.NodeClient]*proto.Version
-	mempool  *Mempool
+	logger         *zap.SugaredLogger
+	peerLock       sync.RWMutex
+	peers          map[string]*peer
+	bootstrapNodes []string
+	mempool        *Mempool
+	chain          *Chain
+	addrBook       *AddrBook
+	bans           *BanList
+	requestLock    sync.Mutex
+	requested      map[string]time.Time
+	limiter        *rateLimiter
+	syncing        atomic.Bool
+	// votes of the heights that are not final yet
+	votes         *voteBook
+	evidence      *evidencePool
+	challengeLock sync.Mutex
+	challenges    map[string]time.Time
+	serverOption  grpc.ServerOption
+	dialOption    grpc.DialOption
+	// dial connects to the node listening on an address, tests replace it
+	dial func(addr string) (*peer, error)
+	// credentialsErr is returned by Serve when the transport could not be set up
+	credentialsErr error
+	// genesisErr is returned by Serve when the genesis is invalid
+	genesisErr error
+
+	// ctx is cancelled by Stop, all background work derives from it
+	ctx      context.Context
+	cancel   context.CancelFunc
+	loops    sync.WaitGroup
+	server   *grpc.Server
+	lifeLock sync.Mutex
+	stopOnce sync.Once
 	proto.UnimplementedNodeServer
 }
 
@@ -88,150 +197,618 @@ func NewNode(cfg ServerConfig) *Node {
 	loggerConfig.EncoderConfig.TimeKey = ""
 	logger, _ := loggerConfig.Build()
 
-	return &Node{
-		peers:        make(map[proto.NodeClient]*proto.Version),
+	if len(cfg.Version) == 0 {
+		cfg.Version = defaultVersion
+	}
+	if len(cfg.NetworkID) == 0 {
+		cfg.NetworkID = DefaultNetworkID
+	}
+	if cfg.NodeKey == nil {
+		cfg.NodeKey = crypto.GeneratePrivateKey()
+	}
+	if cfg.Genesis == nil {
+		cfg.Genesis = DevGenesis(cfg.Params, cfg.Validators...)
+	}
+	chain, genesisErr := NewChainFromGenesis(NewMemoryBlockStore(), NewMemoryTXStore(), cfg.Genesis)
+	if genesisErr != nil {
+		chain = NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+	}
+	cfg.Params = chain.Params()
+	if cfg.Engine == nil {
+		cfg.Engine = NewAuthorityEngine(cfg.Params.BlockTime)
+	}
+	if cfg.MaxInbound == 0 {
+		cfg.MaxInbound = defaultMaxInbound
+	}
+	if cfg.MaxOutbound == 0 {
+		cfg.MaxOutbound = defaultMaxOutbound
+	}
+	addrBook, err := NewAddrBook(cfg.AddrBookPath)
+	if err != nil {
+		logger.Sugar().Errorw("could not load address book, starting empty", "path", cfg.AddrBookPath, "err", err)
+		addrBook, _ = NewAddrBook("")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	n := &Node{
+		ctx:          ctx,
+		cancel:       cancel,
+		peers:        make(map[string]*peer),
 		logger:       logger.Sugar(),
 		mempool:      NewMempool(),
+		chain:        chain,
+		addrBook:     addrBook,
+		bans:         NewBanList(),
+		requested:    make(map[string]time.Time),
+		limiter:      newRateLimiter(),
+		votes:        newVoteBook(),
+		evidence:     newEvidencePool(),
+		challenges:   make(map[string]time.Time),
+		genesisErr:   genesisErr,
 		ServerConfig: cfg,
 	}
+	n.chain.SetEngine(cfg.Engine)
+	n.serverOption, n.dialOption, n.credentialsErr = n.transportCredentials()
+	n.dial = func(addr string) (*peer, error) {
+		return newPeer(addr, n.dialOption)
+	}
+	return n
 }
 
-func (n *Node) Start(listenAddr string, bootstrapNodes []string) error {
+// Start serves the node until ctx is cancelled or Stop is called.
+func (n *Node) Start(ctx context.Context, listenAddr string, bootstrapNodes []string) error {
 	n.ListenAddr = listenAddr
 	ln, err := net.Listen("tcp", listenAddr)
 	if err != nil {
 		return err
 	}
+	return n.Serve(ctx, ln, bootstrapNodes)
+}
+
+// Serve is Start on a listener that is already open. Peers dial the node
+// at the address of the listener unless ListenAddr is set.
+func (n *Node) Serve(ctx context.Context, ln net.Listener, bootstrapNodes []string) error {
+	if len(n.ListenAddr) == 0 {
+		n.ListenAddr = ln.Addr().String()
+	}
+	if n.PrivateKey == nil && len(n.KeystoreFile) > 0 {
+		privKey, err := crypto.LoadKeystore(n.KeystoreFile, n.KeystorePassword)
+		if err != nil {
+			ln.Close()
+			return err
+		}
+		n.PrivateKey = privKey
+	}
+
+	if n.credentialsErr != nil {
+		ln.Close()
+		return n.credentialsErr
+	}
+	if n.genesisErr != nil {
+		ln.Close()
+		return n.genesisErr
+	}
 
 	// creating a new grpc server
 	var (
-		opts       = []grpc.ServerOption{}
+		opts       = append(n.limitOptions(), n.serverOption)
 		grpcServer = grpc.NewServer(opts...)
 	)
 	// generated function, regestering new server
 	proto.RegisterNodeServer(grpcServer, n)
 
-	n.logger.Infow("node started...", "port:", n.ListenAddr)
-
-	if len(bootstrapNodes) > 0 {
-		go n.bootstrapNetwork(bootstrapNodes)
+	n.lifeLock.Lock()
+	if n.ctx.Err() != nil {
+		n.lifeLock.Unlock()
+		ln.Close()
+		return nil
 	}
+	n.server = grpcServer
+	n.lifeLock.Unlock()
+
+	n.logger.Infow("node started...", "port:", n.ListenAddr, "nodeKey", n.NodeKey.Public(), "tls", n.TLS)
 
+	n.bootstrapNodes = bootstrapNodes
+	for _, addr := range bootstrapNodes {
+		n.addrBook.Add(addr, "bootstrap")
+	}
+	n.goLoop(n.connectLoop)
+	n.goLoop(n.pingLoop)
 	if n.PrivateKey != nil {
-		go n.validatorLoop()
+		n.goLoop(n.validatorLoop)
 	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			n.Stop()
+		case <-n.ctx.Done():
+		}
+	}()
 
-	return grpcServer.Serve(ln)
+	if err := grpcServer.Serve(ln); err != nil && n.ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
+
+func (n *Node) goLoop(loop func()) {
+	n.loops.Add(1)
+	go func() {
+		defer n.loops.Done()
+		loop()
+	}()
+}
+
+// Stop shuts the node down: background loops are stopped, in-flight RPCs
+// finish, queued broadcasts are delivered, peer connections are closed and
+// the address book and stores are flushed. Stop can be called more than
+// once, also before Start.
+func (n *Node) Stop() error {
+	var err error
+	n.stopOnce.Do(func() {
+		n.lifeLock.Lock()
+		n.cancel()
+		server := n.server
+		n.lifeLock.Unlock()
+
+		if server != nil {
+			gracefulStop(server, shutdownTimeout)
+		}
+		n.loops.Wait()
+		n.closePeers()
+
+		if saveErr := n.addrBook.Save(); saveErr != nil {
+			err = saveErr
+		}
+		if closeErr := n.chain.Close(); closeErr != nil {
+			err = closeErr
+		}
+		n.logger.Infow("node stopped", "we", n.ListenAddr)
+	})
+	return err
+}
+
+// gracefulStop waits for in-flight RPCs, at most timeout long.
+func gracefulStop(server *grpc.Server, timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		server.Stop()
+	}
+}
+
+// closePeers delivers the queued messages of all peers before closing
+// their connections.
+func (n *Node) closePeers() {
+	n.peerLock.Lock()
+	peers := n.peers
+	n.peers = make(map[string]*peer)
+	n.peerLock.Unlock()
+
+	var wg sync.WaitGroup
+	for _, p := range peers {
+		wg.Add(1)
+		go func(p *peer) {
+			defer wg.Done()
+			p.stop(drainTimeout)
+		}(p)
+	}
+	wg.Wait()
 }
 
 func (n *Node) Handshake(ctx context.Context, v *proto.Version) (*proto.Version, error) {
+	if n.isBanned(ctx, v.ListenAddr) {
+		return nil, fmt.Errorf("peer %s is banned", v.ListenAddr)
+	}
+	if err := n.authenticate(ctx, v); err != nil {
+		n.logger.Debugw("rejecting peer", "we", n.ListenAddr, "remote", v.ListenAddr, "err", err)
+		return nil, err
+	}
+	if n.countPeers(false) >= n.MaxInbound {
+		return nil, fmt.Errorf("too many inbound peers (%d)", n.MaxInbound)
+	}
 
-	c, err := makeNodeClient(v.ListenAddr)
+	// the signed version proves the node key, not the listen address
+	p, err := n.dial(v.ListenAddr)
 	if err != nil {
 		return nil, err
 	}
-	n.addPeer(c, v)
+	if err := n.identify(p, v.NodeKey); err != nil {
+		p.close()
+		n.logger.Debugw("rejecting peer", "we", n.ListenAddr, "remote", v.ListenAddr, "err", err)
+		return nil, err
+	}
+	n.addrBook.Add(v.ListenAddr, v.ListenAddr)
+	n.addrBook.MarkGood(v.ListenAddr)
+	n.addPeer(p, v)
+
+	return n.signedVersion(v.Nonce)
+}
+
+// authenticate checks the version of a node dialing us, which has to
+// answer a challenge we handed out before.
+func (n *Node) authenticate(ctx context.Context, v *proto.Version) error {
+	if err := n.checkVersion(v); err != nil {
+		return err
+	}
+	if !n.useChallenge(v.Challenge) {
+		return fmt.Errorf("unknown or expired challenge from peer %s", v.ListenAddr)
+	}
+	if err := n.verifyVersion(v, v.Challenge); err != nil {
+		return err
+	}
+	p, _ := grpcpeer.FromContext(ctx)
+	return checkTransportKey(p, v.NodeKey)
+}
+
+func (n *Node) GetAddr(ctx context.Context, req *proto.AddrRequest) (*proto.AddrList, error) {
+	max := maxAddrsPerMessage
+	if req.Max > 0 {
+		max = min(int(req.Max), max)
+	}
+	return &proto.AddrList{
+		Addrs: n.addrBook.Sample(max),
+	}, nil
+}
 
-	return n.getVersion(), nil
+func (n *Node) Heartbeat(ctx context.Context, ping *proto.Ping) (*proto.Pong, error) {
+	return &proto.Pong{
+		Nonce: ping.Nonce,
+	}, nil
 }
 
+// HandleTransaction is the entry point for transactions of clients, peers
+// relay transactions through Announce and GetData.
 func (n *Node) HandleTransaction(ctx context.Context, tx *proto.Transaction) (*proto.Ack, error) {
-	peer, _ := peer.FromContext(ctx)
+	var from string
+	if peer, ok := grpcpeer.FromContext(ctx); ok {
+		from = peer.Addr.String()
+	}
+	if n.isBanned(ctx, "") {
+		return nil, fmt.Errorf("%s is banned", from)
+	}
 	hash := hex.EncodeToString(types.HashTransaction(tx))
+	if n.mempool.Has(tx) {
+		return &proto.Ack{}, nil
+	}
+	if err := n.chain.ValidateTransaction(tx); err != nil {
+		n.clientMisbehaving(ctx, penaltyInvalidTx, "invalid transaction")
+		return nil, fmt.Errorf("invalid tx %s: %w", hash, err)
+	}
 
 	if n.mempool.Add(tx) {
-		n.logger.Debugw("received tx", "from", peer.Addr, "hash", hash, "we", n.ListenAddr)
-		go func() {
-			if err := n.broadcase(tx); err != nil {
-				n.logger.Errorw("broadcast error", "err", err)
-			}
-		}()
+		n.logger.Debugw("received tx", "from", from, "hash", hash, "we", n.ListenAddr)
+		n.announce(txInvItem(tx))
 	}
 
 	return &proto.Ack{}, nil
 }
 
+// validatorLoop produces a block whenever the consensus engine schedules
+// us, or earlier when the production policy wants one right away. The
+// validator set is read again every time, as governance may add or remove
+// us.
 func (n *Node) validatorLoop() {
-	n.logger.Infow("starting validator loop", "pubKey", n.PrivateKey.Public(), "BlockTime", blockTime)
-	ticker := time.NewTicker(blockTime)
+	var (
+		pubKey = n.PrivateKey.Public()
+		engine = n.chain.Engine()
+		active = false
+		// since when we wait for a sync
+		waiting time.Time
+	)
+	n.logger.Infow("starting validator loop", "pubKey", pubKey)
 	for {
-		<-ticker.C
+		var (
+			vs     = n.chain.ValidatorSet()
+			parent = n.chain.headers.Get(n.chain.Height())
+			now    = time.Now()
+			added  <-chan struct{}
+		)
+		at, ok := engine.Schedule(vs, parent, pubKey, now)
+		if ok != active {
+			active = ok
+			n.logger.Infow("block production changed", "active", active)
+		}
+		if !ok {
+			at = now.Add(n.Params.BlockTime)
+		}
+		due := false
+		if ok && n.Production.onDemand() {
+			added = n.mempool.Added()
+			if due = n.Production.due(n.mempool.size()); due {
+				if earliest, _ := engine.Earliest(vs, parent, pubKey, now); earliest.Before(at) {
+					at = earliest
+				}
+			}
+		}
+		timer := time.NewTimer(time.Until(at))
+		select {
+		case <-n.ctx.Done():
+			timer.Stop()
+			return
+		case <-added:
+			// the block may be due earlier now
+			timer.Stop()
+			continue
+		case <-timer.C:
+		}
+		if !ok {
+			continue
+		}
+		if txs, _ := n.mempool.size(); n.Production.skip(txs) {
+			n.waitAdded(n.Params.BlockTime)
+			continue
+		}
+		// a block on a stale tip would fork the chain, but a sync that
+		// does not finish must not stop production for good
+		if n.behind() || (vs != nil && vs.Len() > 1 && !n.synced()) {
+			if waiting.IsZero() {
+				waiting = time.Now()
+			}
+			if time.Since(waiting) < maxSyncWait {
+				n.logger.Debugw("not synced, skipping block", "height", n.chain.Height())
+				n.waitTipChanged(n.Params.BlockTime)
+				continue
+			}
+			n.logger.Warnw("not synced, producing anyway", "height", n.chain.Height(), "waited", time.Since(waiting))
+		} else {
+			waiting = time.Time{}
+		}
 		txx := n.mempool.Clear()
 		n.logger.Debugw("time to create a new block", "lenTx", len(txx))
+		err := n.produceBlock(txx)
+		if errors.Is(err, context.Canceled) {
+			n.logger.Debugw("tip changed while sealing", "height", n.chain.Height())
+		} else if err != nil {
+			n.logger.Errorw("failed to produce block", "err", err)
+			if due {
+				// the transactions are back in the mempool, the block
+				// would be due again right away
+				n.waitTipChanged(n.Params.BlockTime)
+			}
+		}
 	}
 }
 
-func (n *Node) broadcase(msg any) error {
-	for peer := range n.peers {
-		switch v := msg.(type) {
-		case *proto.Transaction:
-			_, err := peer.HandleTransaction(context.Background(), v)
-			if err != nil {
-				return err
-			}
+// waitAdded returns when a transaction is added to the mempool, at the
+// latest after d.
+func (n *Node) waitAdded(d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-n.mempool.Added():
+	case <-timer.C:
+	case <-n.ctx.Done():
+	}
+}
+
+// waitTipChanged returns when the tip of the chain changes, at the latest
+// after d.
+func (n *Node) waitTipChanged(d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-n.chain.TipChanged():
+	case <-timer.C:
+	case <-n.ctx.Done():
+	}
+}
+
+// produceBlock seals the valid transactions of txx into the next block.
+func (n *Node) produceBlock(txx []*proto.Transaction) error {
+	var (
+		valid = []*proto.Transaction{}
+		spent = make(map[string]bool)
+	)
+	for _, tx := range txx {
+		if err := n.chain.ValidateTransaction(tx); err != nil {
+			n.logger.Debugw("dropping invalid tx", "hash", hex.EncodeToString(types.HashTransaction(tx)), "err", err)
+			continue
+		}
+		// of the transactions spending the same output the first one wins
+		if spendsAny(tx, spent) {
+			n.logger.Debugw("dropping conflicting tx", "hash", hex.EncodeToString(types.HashTransaction(tx)))
+			continue
+		}
+		for _, input := range tx.Inputs {
+			spent[inputKey(input)] = true
+		}
+		valid = append(valid, tx)
+	}
+
+	tipChanged := n.chain.TipChanged()
+	prevBlock, err := n.chain.GetBlockByHeight(n.chain.Height())
+	if err != nil {
+		return err
+	}
+	// sealing may take a while, a block on a stale tip is useless
+	ctx, cancel := context.WithCancel(n.ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-tipChanged:
+			cancel()
+		case <-ctx.Done():
 		}
+	}()
+	block := &proto.Block{
+		Header: &proto.Header{
+			Version:   1,
+			Height:    prevBlock.Header.Height + 1,
+			PrevHash:  types.HashBlock(prevBlock),
+			Timestamp: time.Now().UnixNano(),
+		},
+		Evidence: n.blockEvidence(),
 	}
+	// what does not fit waits for the next block
+	fit, rest := n.chain.Params().fitBlock(block, valid)
+	for _, tx := range rest {
+		n.mempool.Add(tx)
+	}
+	block.Transactions = fit
+	err = n.chain.Engine().Seal(ctx, n.chain, block, prevBlock.Header, n.PrivateKey)
+	if err == nil {
+		err = n.chain.AddBlock(block)
+	}
+	if err != nil {
+		// the transactions get another chance in the next block
+		for _, tx := range fit {
+			n.mempool.Add(tx)
+		}
+		return err
+	}
+	for _, ev := range block.Evidence {
+		n.evidence.remove(ev)
+	}
+	n.logger.Infow("new block", "height", block.Header.Height, "lenTx", len(fit), "we", n.ListenAddr)
+	n.announce(blockInvItem(block))
+	n.onBlock(block)
 	return nil
 }
 
-func (n *Node) addPeer(c proto.NodeClient, v *proto.Version) {
-	n.peerLock.Lock()
-	defer n.peerLock.Unlock()
+// spendsAny reports whether tx spends one of the spent outputs.
+func spendsAny(tx *proto.Transaction, spent map[string]bool) bool {
+	for _, input := range tx.Inputs {
+		if spent[inputKey(input)] {
+			return true
+		}
+	}
+	return false
+}
 
-	n.peers[c] = v
-	if len(v.PeerList) > 0 {
-		go n.bootstrapNetwork(v.PeerList)
+// Height returns the height of the local chain.
+func (n *Node) Height() int {
+	return n.chain.Height()
+}
+
+func (n *Node) Chain() *Chain {
+	return n.chain
+}
+
+// HasTransaction reports whether the transaction is in the mempool or the
+// chain.
+func (n *Node) HasTransaction(hash []byte) bool {
+	return n.hasItem(&proto.InvItem{Type: proto.InvType_TX, Hash: hash})
+}
+
+// Peers returns the listen addresses of the connected peers.
+func (n *Node) Peers() []string {
+	return n.getPeerList()
+}
+
+// Connect dials the node listening on addr as an outbound peer.
+func (n *Node) Connect(addr string) error {
+	if !n.canConnectWith(addr) {
+		return fmt.Errorf("can't connect with %s", addr)
 	}
+	return n.connect(addr)
+}
 
-	n.logger.Debugf("new peer successfully connected", "we", n.ListenAddr, "remote node", v.ListenAddr, "Height", v.Height)
+// PeerStats returns the outbound queue metrics of every connected peer.
+func (n *Node) PeerStats() map[string]PeerStats {
+	n.peerLock.RLock()
+	defer n.peerLock.RUnlock()
+
+	stats := make(map[string]PeerStats, len(n.peers))
+	for addr, p := range n.peers {
+		stats[addr] = p.stats()
+	}
+	return stats
 }
 
-func (n *Node) deletePeer(c proto.NodeClient) {
+// addPeer registers p unless there is a peer for its address already, p is
+// closed then.
+func (n *Node) addPeer(p *peer, v *proto.Version) bool {
 	n.peerLock.Lock()
 	defer n.peerLock.Unlock()
-	delete(n.peers, c)
-}
 
-func (n *Node) bootstrapNetwork(addrs []string) error {
-	for _, addr := range addrs {
+	if _, ok := n.peers[v.ListenAddr]; ok || n.ctx.Err() != nil {
+		p.close()
+		return false
+	}
+	p.version = v
+	n.peers[v.ListenAddr] = p
+	go p.writeLoop(n.logger)
+	n.learnAddrs(v.PeerList, v.ListenAddr)
 
-		if !n.canConnectWith(addr) {
-			continue
-		}
+	n.logger.Debugf("new peer successfully connected", "we", n.ListenAddr, "remote node", v.ListenAddr, "Height", v.Height)
+	return true
+}
 
-		n.logger.Debugw("dialing remote node ", "we", n.ListenAddr, "remote", addr)
-		c, v, err := n.dialRemoteNode(addr)
-		if err != nil {
-			return err
-		}
-		n.addPeer(c, v)
+func (n *Node) deletePeer(addr string) {
+	n.peerLock.Lock()
+	defer n.peerLock.Unlock()
+	p, ok := n.peers[addr]
+	if !ok {
+		return
 	}
-	return nil
+	p.close()
+	delete(n.peers, addr)
 }
 
-func (n *Node) dialRemoteNode(addr string) (proto.NodeClient, *proto.Version, error) {
-	c, err := makeNodeClient(addr)
+func (n *Node) dialRemoteNode(addr string) (*peer, *proto.Version, error) {
+	p, err := n.dial(addr)
 	if err != nil {
 		return nil, nil, err
 	}
-	v, err := c.Handshake(context.Background(), n.getVersion())
+	v, err := n.handshake(p)
 	if err != nil {
+		p.close()
 		return nil, nil, err
 	}
-	return c, v, nil
+	return p, v, nil
+}
+
+// handshake answers the challenge of p and checks that p answers ours.
+func (n *Node) handshake(p *peer) (*proto.Version, error) {
+	ctx, cancel := context.WithTimeout(n.ctx, requestTimeout)
+	defer cancel()
+	challenge, err := p.GetChallenge(ctx, &proto.Ack{})
+	if err != nil {
+		return nil, err
+	}
+	ours, err := n.signedVersion(challenge.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	var remote grpcpeer.Peer
+	v, err := p.Handshake(ctx, ours, grpc.Peer(&remote))
+	if err != nil {
+		return nil, err
+	}
+	if err := n.checkVersion(v); err != nil {
+		return nil, err
+	}
+	if err := n.verifyVersion(v, ours.Nonce); err != nil {
+		return nil, err
+	}
+	if err := checkTransportKey(&remote, v.NodeKey); err != nil {
+		return nil, err
+	}
+	return v, nil
 }
 
 func (n *Node) getVersion() *proto.Version {
 	return &proto.Version{
-		Version:    "blocker-0.1",
-		Height:     0,
-		ListenAddr: n.ListenAddr,
-		PeerList:   n.getPeerList(),
+		Version:         n.Version,
+		Height:          int32(n.chain.Height()),
+		ListenAddr:      n.ListenAddr,
+		PeerList:        n.getPeerList(),
+		NetworkID:       n.NetworkID,
+		GenesisHash:     n.chain.GenesisHash(),
+		ProtocolVersion: ProtocolVersion,
+		Features:        uint64(supportedFeatures),
+		NodeKey:         n.NodeKey.Public().Bytes(),
 	}
 }
 
 func (n *Node) canConnectWith(addr string) bool {
-	if n.ListenAddr == addr {
+	if n.ListenAddr == addr || n.bans.IsBanned(addr, time.Now()) {
 		return false
 	}
 
@@ -245,20 +822,31 @@ func (n *Node) canConnectWith(addr string) bool {
 	return true
 }
 
-func (n *Node) getPeerList() []string {
+// countPeers returns the number of outbound or inbound peers.
+func (n *Node) countPeers(outbound bool) int {
 	n.peerLock.RLock()
 	defer n.peerLock.RUnlock()
-	peers := []string{}
-	for _, version := range n.peers {
-		peers = append(peers, version.ListenAddr)
+	count := 0
+	for _, p := range n.peers {
+		if p.outbound == outbound {
+			count++
+		}
 	}
-	return peers
+	return count
 }
 
-func makeNodeClient(listenAddr string) (proto.NodeClient, error) {
-	c, err := grpc.Dial(listenAddr, grpc.WithInsecure())
-	if err != nil {
-		return nil, err
+func (n *Node) getPeer(addr string) *peer {
+	n.peerLock.RLock()
+	defer n.peerLock.RUnlock()
+	return n.peers[addr]
+}
+
+func (n *Node) getPeerList() []string {
+	n.peerLock.RLock()
+	defer n.peerLock.RUnlock()
+	peers := []string{}
+	for addr := range n.peers {
+		peers = append(peers, addr)
 	}
-	return proto.NewNodeClient(c), nil
+	return peers
 }
@@ -0,0 +1,105 @@
+package node
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/64bitAryan/blocker/crypto"
+	"github.com/64bitAryan/blocker/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartStop(t *testing.T) {
+	var (
+		addr = freeAddr(t)
+		path = filepath.Join(t.TempDir(), "addrbook.json")
+		n    = NewNode(ServerConfig{
+			PrivateKey:   crypto.GeneratePrivateKey(),
+			AddrBookPath: path,
+		})
+		errc = make(chan error, 1)
+	)
+	go func() {
+		errc <- n.Start(context.Background(), addr, []string{"10.0.0.1:3000"})
+	}()
+	waitForListener(t, addr)
+
+	require.Nil(t, n.Stop())
+	select {
+	case err := <-errc:
+		assert.Nil(t, err)
+	case <-time.After(time.Second * 5):
+		t.Fatal("Start did not return after Stop")
+	}
+	_, err := net.Dial("tcp", addr)
+	assert.NotNil(t, err)
+	assert.Nil(t, n.Stop())
+
+	// the address book is flushed on shutdown
+	book, err := NewAddrBook(path)
+	require.Nil(t, err)
+	_, ok := book.Get("10.0.0.1:3000")
+	assert.True(t, ok)
+}
+
+func TestCancelStopsNode(t *testing.T) {
+	var (
+		addr        = freeAddr(t)
+		n           = NewNode(ServerConfig{})
+		ctx, cancel = context.WithCancel(context.Background())
+		errc        = make(chan error, 1)
+	)
+	go func() {
+		errc <- n.Start(ctx, addr, []string{})
+	}()
+	waitForListener(t, addr)
+
+	cancel()
+	select {
+	case err := <-errc:
+		assert.Nil(t, err)
+	case <-time.After(time.Second * 5):
+		t.Fatal("Start did not return after the context was cancelled")
+	}
+	assert.NotNil(t, n.ctx.Err())
+}
+
+func TestStopBeforeStart(t *testing.T) {
+	n := NewNode(ServerConfig{})
+	require.Nil(t, n.Stop())
+	assert.Nil(t, n.Start(context.Background(), freeAddr(t), []string{}))
+}
+
+func TestStopDrainsQueuedMessages(t *testing.T) {
+	var (
+		n      = NewNode(ServerConfig{ListenAddr: ":3000"})
+		client = &fakeNodeClient{alive: true, block: make(chan struct{})}
+	)
+	addFakePeer(n, ":4000", client)
+	for i := 0; i < 3; i++ {
+		n.announce(txInvItem(&proto.Transaction{Version: int32(i)}))
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		n.Stop()
+		close(stopped)
+	}()
+	close(client.block)
+
+	select {
+	case <-stopped:
+	case <-time.After(drainTimeout * 2):
+		t.Fatal("Stop did not return")
+	}
+	assert.Equal(t, int64(3), client.received.Load())
+	assert.Empty(t, n.getPeerList())
+
+	// no new peers once the node is stopped
+	addFakePeer(n, ":5000", &fakeNodeClient{alive: true})
+	assert.Empty(t, n.getPeerList())
+}
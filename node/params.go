@@ -0,0 +1,104 @@
+package node
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/64bitAryan/blocker/proto"
+	"google.golang.org/protobuf/encoding/protowire"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// room kept in a block for what sealing adds to the header
+const sealReserve = 256
+
+// ChainParams tune the throughput of a chain. They are part of the genesis
+// block, so nodes with other parameters are on another chain.
+type ChainParams struct {
+	// BlockTime is the interval validators produce blocks at
+	BlockTime time.Duration
+	// MaxBlockBytes limits the size of a marshalled block
+	MaxBlockBytes int
+	MaxBlockTxs   int
+	// MaxTxBytes limits the size of a marshalled transaction
+	MaxTxBytes int
+}
+
+func DefaultChainParams() ChainParams {
+	return ChainParams{
+		BlockTime:     time.Second * 5,
+		MaxBlockBytes: 1 << 20,
+		MaxBlockTxs:   5000,
+		MaxTxBytes:    100 << 10,
+	}
+}
+
+// withDefaults returns p with the default of every parameter not set.
+func (p ChainParams) withDefaults() ChainParams {
+	defaults := DefaultChainParams()
+	if p.BlockTime == 0 {
+		p.BlockTime = defaults.BlockTime
+	}
+	if p.MaxBlockBytes == 0 {
+		p.MaxBlockBytes = defaults.MaxBlockBytes
+	}
+	if p.MaxBlockTxs == 0 {
+		p.MaxBlockTxs = defaults.MaxBlockTxs
+	}
+	if p.MaxTxBytes == 0 {
+		p.MaxTxBytes = defaults.MaxTxBytes
+	}
+	return p
+}
+
+func (p ChainParams) toProto() *proto.ChainParams {
+	return &proto.ChainParams{
+		BlockTime:     int64(p.BlockTime),
+		MaxBlockBytes: int64(p.MaxBlockBytes),
+		MaxBlockTxs:   int64(p.MaxBlockTxs),
+		MaxTxBytes:    int64(p.MaxTxBytes),
+	}
+}
+
+func (p ChainParams) checkTx(tx *proto.Transaction) error {
+	if size := pb.Size(tx); size > p.MaxTxBytes {
+		return fmt.Errorf("transaction of %d bytes, at most %d", size, p.MaxTxBytes)
+	}
+	return nil
+}
+
+func (p ChainParams) checkBlock(b *proto.Block) error {
+	if len(b.Transactions) > p.MaxBlockTxs {
+		return fmt.Errorf("block with %d transactions, at most %d", len(b.Transactions), p.MaxBlockTxs)
+	}
+	if size := pb.Size(b); size > p.MaxBlockBytes {
+		return fmt.Errorf("block of %d bytes, at most %d", size, p.MaxBlockBytes)
+	}
+	for _, tx := range b.Transactions {
+		if err := p.checkTx(tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fitBlock splits txx into the transactions that fit into b, in order,
+// and the rest.
+func (p ChainParams) fitBlock(b *proto.Block, txx []*proto.Transaction) ([]*proto.Transaction, []*proto.Transaction) {
+	var (
+		size = pb.Size(b) + sealReserve
+		fit  = []*proto.Transaction{}
+		rest = []*proto.Transaction{}
+	)
+	for _, tx := range txx {
+		// the transactions field takes a tag byte and the length
+		txSize := 1 + protowire.SizeBytes(pb.Size(tx))
+		if len(b.Transactions)+len(fit) == p.MaxBlockTxs || size+txSize > p.MaxBlockBytes {
+			rest = append(rest, tx)
+			continue
+		}
+		size += txSize
+		fit = append(fit, tx)
+	}
+	return fit, rest
+}
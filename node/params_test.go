@@ -0,0 +1,69 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/64bitAryan/blocker/crypto"
+	"github.com/64bitAryan/blocker/proto"
+	"github.com/64bitAryan/blocker/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// sizedTx returns a transaction of about size bytes.
+func sizedTx(size int) *proto.Transaction {
+	return &proto.Transaction{
+		Version: 1,
+		Outputs: []*proto.TxOutput{{Amount: 1, Address: make([]byte, size)}},
+	}
+}
+
+func TestChainParamsDefaults(t *testing.T) {
+	params := ChainParams{BlockTime: time.Second}.withDefaults()
+	assert.Equal(t, time.Second, params.BlockTime)
+	assert.Equal(t, DefaultChainParams().MaxBlockBytes, params.MaxBlockBytes)
+
+	chain := NewChainWithParams(NewMemoryBlockStore(), NewMemoryTXStore(), params)
+	assert.Equal(t, params, chain.Params())
+	assert.NotEqual(t, NewChain(NewMemoryBlockStore(), NewMemoryTXStore()).GenesisHash(), chain.GenesisHash())
+}
+
+func TestFitBlock(t *testing.T) {
+	var (
+		params = ChainParams{MaxBlockBytes: 2048, MaxBlockTxs: 3}.withDefaults()
+		b      = &proto.Block{Header: &proto.Header{Version: 1, Height: 1}}
+		txx    = []*proto.Transaction{sizedTx(10), sizedTx(10), sizedTx(3000), sizedTx(10), sizedTx(10)}
+	)
+	fit, rest := params.fitBlock(b, txx)
+	assert.Equal(t, []*proto.Transaction{txx[0], txx[1], txx[3]}, fit)
+	assert.Equal(t, []*proto.Transaction{txx[2], txx[4]}, rest)
+
+	b.Transactions = fit
+	assert.LessOrEqual(t, pb.Size(b)+sealReserve, params.MaxBlockBytes)
+
+	params.MaxBlockBytes = pb.Size(b) + sealReserve
+	fit, rest = params.fitBlock(b, []*proto.Transaction{sizedTx(1)})
+	assert.Empty(t, fit)
+	assert.Len(t, rest, 1)
+}
+
+func TestChainParamsLimits(t *testing.T) {
+	chain := NewChainWithParams(NewMemoryBlockStore(), NewMemoryTXStore(), ChainParams{
+		MaxBlockBytes: 8192,
+		MaxBlockTxs:   2,
+		MaxTxBytes:    1024,
+	})
+	assert.ErrorContains(t, chain.ValidateTransaction(sizedTx(2048)), "at most 1024")
+
+	b := randomBlock(t, chain)
+	b.Transactions = []*proto.Transaction{sizedTx(1), sizedTx(1), sizedTx(1)}
+	types.SignBlock(crypto.GeneratePrivateKey(), b)
+	assert.ErrorContains(t, chain.AddBlock(b), "3 transactions")
+
+	b.Transactions = []*proto.Transaction{sizedTx(5000), sizedTx(5000)}
+	types.SignBlock(crypto.GeneratePrivateKey(), b)
+	assert.ErrorContains(t, chain.AddBlock(b), "block of")
+	require.Equal(t, 0, chain.Height())
+}
@@ -0,0 +1,352 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/64bitAryan/blocker/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+const (
+	pingInterval    = time.Second * 10
+	pingTimeout     = time.Second * 2
+	maxPingFailures = 3
+
+	sendQueueSize = 256
+	sendTimeout   = time.Second * 5
+
+	reconnectInterval = time.Second
+	minBackoff        = time.Second
+	maxBackoff        = time.Minute * 2
+)
+
+type PeerStats struct {
+	Queued  int
+	Sent    uint64
+	Dropped uint64
+	Failed  uint64
+}
+
+type peer struct {
+	proto.NodeClient
+	conn    *grpc.ClientConn
+	version *proto.Version
+	// outbound peers were dialed by us, inbound peers dialed us
+	outbound bool
+	// consecutive failed pings, only touched by the ping loop
+	failures int
+	// best chain height the remote node is known to have
+	height atomic.Int64
+
+	// inventory the remote node is known to have
+	known     *inventorySet
+	send      chan any
+	quit      chan struct{}
+	drain     chan struct{} // asks the write loop to deliver the queue and exit
+	done      chan struct{} // closed once the write loop exited
+	closeOnce sync.Once
+	drainOnce sync.Once
+	sent      atomic.Uint64
+	dropped   atomic.Uint64
+	failed    atomic.Uint64
+}
+
+func newPeer(listenAddr string, opts ...grpc.DialOption) (*peer, error) {
+	conn, err := grpc.Dial(listenAddr, append(dialLimitOptions(), opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	p := newPeerFromClient(proto.NewNodeClient(conn))
+	p.conn = conn
+	return p, nil
+}
+
+func newPeerFromClient(c proto.NodeClient) *peer {
+	return &peer{
+		NodeClient: c,
+		known:      newInventorySet(),
+		send:       make(chan any, sendQueueSize),
+		quit:       make(chan struct{}),
+		drain:      make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+func (p *peer) seenHeight(height int64) {
+	for {
+		known := p.height.Load()
+		if height <= known || p.height.CompareAndSwap(known, height) {
+			return
+		}
+	}
+}
+
+func (p *peer) close() error {
+	var err error
+	p.closeOnce.Do(func() {
+		close(p.quit)
+		if p.conn != nil {
+			err = p.conn.Close()
+		}
+	})
+	return err
+}
+
+// enqueue reports false when msg was dropped because the queue is full.
+func (p *peer) enqueue(msg any) bool {
+	select {
+	case p.send <- msg:
+		return true
+	default:
+		p.dropped.Add(1)
+		return false
+	}
+}
+
+// stop delivers the queued messages, at most timeout long, and closes the
+// peer.
+func (p *peer) stop(timeout time.Duration) error {
+	p.drainOnce.Do(func() {
+		close(p.drain)
+	})
+	select {
+	case <-p.done:
+	case <-time.After(timeout):
+	}
+	return p.close()
+}
+
+// writeLoop delivers queued messages one at a time until the peer is closed.
+func (p *peer) writeLoop(logger *zap.SugaredLogger) {
+	defer close(p.done)
+	for {
+		select {
+		case <-p.quit:
+			return
+		case msg := <-p.send:
+			p.write(msg, logger)
+		case <-p.drain:
+			for {
+				select {
+				case <-p.quit:
+					return
+				case msg := <-p.send:
+					p.write(msg, logger)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (p *peer) write(msg any, logger *zap.SugaredLogger) {
+	if err := p.deliver(msg); err != nil {
+		p.failed.Add(1)
+		logger.Debugw("send failed", "remote", p.version.ListenAddr, "err", err)
+		return
+	}
+	p.sent.Add(1)
+}
+
+func (p *peer) deliver(msg any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+
+	switch v := msg.(type) {
+	case *proto.Transaction:
+		_, err := p.HandleTransaction(ctx, v)
+		return err
+	case *proto.Inventory:
+		_, err := p.Announce(ctx, v)
+		return err
+	case *proto.Vote:
+		_, err := p.HandleVote(ctx, v)
+		return err
+	case *proto.Evidence:
+		_, err := p.HandleEvidence(ctx, v)
+		return err
+	}
+	return fmt.Errorf("unknown message type %T", msg)
+}
+
+func (p *peer) stats() PeerStats {
+	return PeerStats{
+		Queued:  len(p.send),
+		Sent:    p.sent.Load(),
+		Dropped: p.dropped.Load(),
+		Failed:  p.failed.Load(),
+	}
+}
+
+func (n *Node) pingLoop() {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		n.pingPeers()
+	}
+}
+
+// pingPeers removes peers that failed to answer maxPingFailures pings in a row.
+func (n *Node) pingPeers() {
+	n.peerLock.RLock()
+	peers := make(map[string]*peer, len(n.peers))
+	for addr, p := range n.peers {
+		peers[addr] = p
+	}
+	n.peerLock.RUnlock()
+
+	for addr, p := range peers {
+		ctx, cancel := context.WithTimeout(n.ctx, pingTimeout)
+		nonce := rand.Uint64()
+		pong, err := p.Heartbeat(ctx, &proto.Ping{Nonce: nonce})
+		cancel()
+
+		if err == nil && pong.Nonce == nonce {
+			p.failures = 0
+			n.addrBook.MarkGood(addr)
+			continue
+		}
+		p.failures++
+		n.logger.Debugw("ping failed", "we", n.ListenAddr, "remote", addr, "failures", p.failures, "err", err)
+		if p.failures >= maxPingFailures {
+			n.logger.Infow("removing unresponsive peer", "we", n.ListenAddr, "remote", addr)
+			n.addrBook.MarkFailed(addr)
+			n.deletePeer(addr)
+		}
+	}
+}
+
+type backoff struct {
+	delay time.Duration
+	next  time.Time
+}
+
+func (b *backoff) fail(now time.Time) {
+	b.delay = min(max(b.delay*2, minBackoff), maxBackoff)
+	b.next = now.Add(b.delay)
+}
+
+// connectLoop keeps the outbound slots filled. Bootstrap nodes are
+// reconnected first, backing off exponentially for every node that can't
+// be reached, the remaining slots are filled from the address book.
+func (n *Node) connectLoop() {
+	var (
+		ticker   = time.NewTicker(reconnectInterval)
+		backoffs = make(map[string]*backoff)
+		lastSave = time.Now()
+	)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		now := time.Now()
+		n.reconnect(backoffs, now)
+		n.fillOutbound(now)
+		if now.Sub(lastSave) >= addrBookSaveInterval {
+			if err := n.addrBook.Save(); err != nil {
+				n.logger.Errorw("could not save address book", "err", err)
+			}
+			lastSave = now
+		}
+	}
+}
+
+func (n *Node) reconnect(backoffs map[string]*backoff, now time.Time) {
+	for _, addr := range n.bootstrapNodes {
+		if !n.canConnectWith(addr) {
+			delete(backoffs, addr)
+			continue
+		}
+		if n.countPeers(true) >= n.MaxOutbound {
+			return
+		}
+		b, ok := backoffs[addr]
+		if !ok {
+			b = &backoff{}
+			backoffs[addr] = b
+		}
+		if now.Before(b.next) {
+			continue
+		}
+
+		if err := n.connect(addr); err != nil {
+			b.fail(now)
+			n.logger.Debugw("reconnect failed", "we", n.ListenAddr, "remote", addr, "retry in", b.delay)
+			continue
+		}
+		delete(backoffs, addr)
+	}
+}
+
+func (n *Node) fillOutbound(now time.Time) {
+	need := n.MaxOutbound - n.countPeers(true)
+	if need <= 0 {
+		return
+	}
+	addrs := n.addrBook.Pick(need, now, func(addr string) bool {
+		return !n.canConnectWith(addr)
+	})
+	for _, addr := range addrs {
+		if err := n.connect(addr); err != nil {
+			n.logger.Debugw("dial failed", "we", n.ListenAddr, "remote", addr, "err", err)
+		}
+	}
+}
+
+// connect dials addr as an outbound peer and asks it for more addresses.
+func (n *Node) connect(addr string) error {
+	n.addrBook.Add(addr, addr)
+	n.addrBook.MarkAttempt(addr)
+	n.logger.Debugw("dialing remote node", "we", n.ListenAddr, "remote", addr)
+
+	p, v, err := n.dialRemoteNode(addr)
+	if err != nil {
+		n.addrBook.MarkFailed(addr)
+		return err
+	}
+	n.addrBook.MarkGood(addr)
+	p.outbound = true
+	// the remote may have dialed us meanwhile
+	if n.addPeer(p, v) {
+		go n.requestAddrs(p)
+	}
+	return nil
+}
+
+func (n *Node) requestAddrs(p *peer) {
+	if !Features(p.version.Features).Has(FeatureAddrExchange) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(n.ctx, requestTimeout)
+	defer cancel()
+	resp, err := p.GetAddr(ctx, &proto.AddrRequest{Max: maxAddrsPerMessage})
+	if err != nil {
+		n.logger.Debugw("get addr failed", "we", n.ListenAddr, "remote", p.version.ListenAddr, "err", err)
+		return
+	}
+	n.learnAddrs(resp.Addrs, p.version.ListenAddr)
+}
+
+// learnAddrs adds at most maxAddrsPerMessage addresses told by source.
+func (n *Node) learnAddrs(addrs []string, source string) {
+	for _, addr := range addrs[:min(len(addrs), maxAddrsPerMessage)] {
+		if addr != n.ListenAddr {
+			n.addrBook.Add(addr, source)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/64bitAryan/blocker/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+type fakeNodeClient struct {
+	proto.NodeClient
+	alive bool
+	// Announce blocks until block is closed
+	block    chan struct{}
+	received atomic.Int64
+	// returned by GetData, unless source is set which answers GetData and
+	// GetBlocks instead
+	items  *proto.Items
+	source *Node
+}
+
+func (c *fakeNodeClient) GetData(ctx context.Context, in *proto.Inventory, opts ...grpc.CallOption) (*proto.Items, error) {
+	if c.source != nil {
+		return c.source.GetData(ctx, in)
+	}
+	return c.items, nil
+}
+
+func (c *fakeNodeClient) GetBlocks(ctx context.Context, in *proto.Locator, opts ...grpc.CallOption) (*proto.Items, error) {
+	if c.source != nil {
+		return c.source.GetBlocks(ctx, in)
+	}
+	return &proto.Items{}, nil
+}
+
+func (c *fakeNodeClient) Announce(ctx context.Context, in *proto.Inventory, opts ...grpc.CallOption) (*proto.Ack, error) {
+	if c.block != nil {
+		select {
+		case <-c.block:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	c.received.Add(1)
+	return &proto.Ack{}, nil
+}
+
+func (c *fakeNodeClient) HandleVote(ctx context.Context, in *proto.Vote, opts ...grpc.CallOption) (*proto.Ack, error) {
+	c.received.Add(1)
+	return &proto.Ack{}, nil
+}
+
+func (c *fakeNodeClient) HandleEvidence(ctx context.Context, in *proto.Evidence, opts ...grpc.CallOption) (*proto.Ack, error) {
+	c.received.Add(1)
+	return &proto.Ack{}, nil
+}
+
+func (c *fakeNodeClient) Heartbeat(ctx context.Context, in *proto.Ping, opts ...grpc.CallOption) (*proto.Pong, error) {
+	if !c.alive {
+		return nil, fmt.Errorf("connection refused")
+	}
+	return &proto.Pong{Nonce: in.Nonce}, nil
+}
+
+func (c *fakeNodeClient) Identify(ctx context.Context, in *proto.Challenge, opts ...grpc.CallOption) (*proto.Version, error) {
+	if c.source == nil {
+		return nil, fmt.Errorf("connection refused")
+	}
+	return c.source.Identify(ctx, in)
+}
+
+// dialNodes makes n reach nodes by their listen address without a network.
+func dialNodes(n *Node, nodes ...*Node) {
+	n.dial = func(addr string) (*peer, error) {
+		for _, remote := range nodes {
+			if remote.ListenAddr == addr {
+				return newPeerFromClient(&fakeNodeClient{alive: true, source: remote}), nil
+			}
+		}
+		return newPeerFromClient(&fakeNodeClient{}), nil
+	}
+}
+
+func addFakePeer(n *Node, addr string, client proto.NodeClient) {
+	n.addPeer(newPeerFromClient(client), &proto.Version{ListenAddr: addr})
+}
+
+func TestPingPeersRemovesUnresponsive(t *testing.T) {
+	var (
+		n    = NewNode(ServerConfig{ListenAddr: ":3000"})
+		dead = &fakeNodeClient{alive: false}
+	)
+	addFakePeer(n, ":4000", &fakeNodeClient{alive: true})
+	addFakePeer(n, ":5000", dead)
+
+	for i := 0; i < maxPingFailures-1; i++ {
+		n.pingPeers()
+	}
+	assert.ElementsMatch(t, []string{":4000", ":5000"}, n.getPeerList())
+
+	// one successful ping resets the failure counter
+	dead.alive = true
+	n.pingPeers()
+	dead.alive = false
+	for i := 0; i < maxPingFailures-1; i++ {
+		n.pingPeers()
+	}
+	assert.ElementsMatch(t, []string{":4000", ":5000"}, n.getPeerList())
+
+	n.pingPeers()
+	assert.Equal(t, []string{":4000"}, n.getPeerList())
+}
+
+func TestReconnectBackoff(t *testing.T) {
+	var (
+		n        = NewNode(ServerConfig{ListenAddr: ":3000"})
+		addr     = "127.0.0.1:1"
+		backoffs = make(map[string]*backoff)
+		now      = time.Now()
+	)
+	n.bootstrapNodes = []string{addr}
+
+	n.reconnect(backoffs, now)
+	require.Contains(t, backoffs, addr)
+	assert.Equal(t, minBackoff, backoffs[addr].delay)
+
+	// not retried before the backoff expired
+	n.reconnect(backoffs, now.Add(minBackoff/2))
+	assert.Equal(t, minBackoff, backoffs[addr].delay)
+
+	n.reconnect(backoffs, now.Add(minBackoff))
+	assert.Equal(t, 2*minBackoff, backoffs[addr].delay)
+
+	b := &backoff{delay: maxBackoff}
+	b.fail(now)
+	assert.Equal(t, maxBackoff, b.delay)
+}
+
+func TestAnnounceSlowPeer(t *testing.T) {
+	var (
+		n    = NewNode(ServerConfig{ListenAddr: ":3000"})
+		fast = &fakeNodeClient{alive: true}
+		slow = &fakeNodeClient{alive: true, block: make(chan struct{})}
+		nTx  = sendQueueSize + 10
+	)
+	addFakePeer(n, ":4000", fast)
+	addFakePeer(n, ":5000", slow)
+	defer n.deletePeer(":4000")
+	defer n.deletePeer(":5000")
+
+	// the slow peer does not hold back the fast one
+	for i := 0; i < nTx; i++ {
+		n.announce(txInvItem(&proto.Transaction{Version: int32(i)}))
+		if i%sendQueueSize == sendQueueSize-1 {
+			require.Eventually(t, func() bool {
+				return fast.received.Load() == int64(i+1)
+			}, time.Second*5, time.Millisecond*10)
+		}
+	}
+	require.Eventually(t, func() bool {
+		return fast.received.Load() == int64(nTx)
+	}, time.Second*5, time.Millisecond*10)
+
+	stats := n.PeerStats()
+	assert.Equal(t, uint64(nTx), stats[":4000"].Sent)
+	assert.Zero(t, stats[":4000"].Dropped)
+	assert.GreaterOrEqual(t, stats[":5000"].Dropped, uint64(nTx-sendQueueSize-1))
+
+	close(slow.block)
+	require.Eventually(t, func() bool {
+		return n.PeerStats()[":5000"].Queued == 0
+	}, time.Second*5, time.Millisecond*10)
+}
@@ -0,0 +1,198 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/64bitAryan/blocker/crypto"
+	"github.com/64bitAryan/blocker/proto"
+	"github.com/64bitAryan/blocker/types"
+	pb "google.golang.org/protobuf/proto"
+)
+
+const (
+	// proof of work blocks are final after this many confirmations
+	defaultConfirmations = 6
+	// blocks may be timestamped this far ahead of our clock
+	maxFutureBlockTime = time.Second * 15
+	// the target is adjusted to the block times every this many blocks
+	retargetWindow = 10
+	// a retarget changes the target at most by this factor
+	maxRetargetFactor = 4
+	// miners check for cancellation every this many nonces
+	nonceBatch = 1 << 10
+)
+
+// the easiest target, every hash meets it
+var maxTarget = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+// ProofOfWorkEngine lets anyone produce a block by finding a nonce that
+// makes the header hash a number at or below the target of the header.
+// The target follows the timestamps of the recent blocks, so that blocks
+// come every blockTime.
+type ProofOfWorkEngine struct {
+	initialTarget *big.Int
+	blockTime     time.Duration
+	// number of goroutines mining
+	workers int
+}
+
+// NewProofOfWorkEngine starts with a target of difficulty leading zero
+// bits.
+func NewProofOfWorkEngine(difficulty int, blockTime time.Duration) *ProofOfWorkEngine {
+	if difficulty < 0 || difficulty > 255 {
+		panic("difficulty out of range")
+	}
+	if blockTime <= 0 {
+		panic("block time must be positive")
+	}
+	return &ProofOfWorkEngine{
+		initialTarget: new(big.Int).Rsh(maxTarget, uint(difficulty)),
+		blockTime:     blockTime,
+		workers:       runtime.NumCPU(),
+	}
+}
+
+// Schedule lets every miner start right away, the work paces the blocks.
+func (e *ProofOfWorkEngine) Schedule(vs *ValidatorSet, parent *proto.Header, pubKey *crypto.PublicKeys, now time.Time) (time.Time, bool) {
+	return now, true
+}
+
+func (e *ProofOfWorkEngine) Earliest(vs *ValidatorSet, parent *proto.Header, pubKey *crypto.PublicKeys, now time.Time) (time.Time, bool) {
+	return now, true
+}
+
+func (e *ProofOfWorkEngine) Seal(ctx context.Context, chain HeaderReader, b *proto.Block, parent *proto.Header, privKey *crypto.PrivateKeys) error {
+	target, err := e.NextTarget(chain, parent)
+	if err != nil {
+		return err
+	}
+	b.Header.Target = target.Bytes()
+	// signing sets the merkle root, which the work has to cover
+	types.SignBlock(privKey, b)
+	nonce, err := mine(ctx, b.Header, target, e.workers)
+	if err != nil {
+		return err
+	}
+	b.Header.Nonce = nonce
+	types.SignBlock(privKey, b)
+	return nil
+}
+
+func (e *ProofOfWorkEngine) VerifySeal(chain HeaderReader, vs *ValidatorSet, b *proto.Block, parent *proto.Header, now time.Time) error {
+	if b.Header.Timestamp <= parent.Timestamp {
+		return fmt.Errorf("block timestamp (%d) is not after its parent (%d)", b.Header.Timestamp, parent.Timestamp)
+	}
+	if b.Header.Timestamp > now.Add(maxFutureBlockTime).UnixNano() {
+		return fmt.Errorf("block timestamp (%d) is in the future", b.Header.Timestamp)
+	}
+	target, err := e.NextTarget(chain, parent)
+	if err != nil {
+		return err
+	}
+	if e.target(b.Header).Cmp(target) != 0 {
+		return fmt.Errorf("block target %x, expected %x", b.Header.Target, target)
+	}
+	if !meetsTarget(types.HashBlock(b), target) {
+		return fmt.Errorf("block hash does not meet its target")
+	}
+	return nil
+}
+
+// Work is the expected number of hashes to meet the target of h.
+func (e *ProofOfWorkEngine) Work(h *proto.Header) *big.Int {
+	work := new(big.Int).Lsh(big.NewInt(1), 256)
+	return work.Div(work, new(big.Int).Add(e.target(h), big.NewInt(1)))
+}
+
+func (e *ProofOfWorkEngine) FinalityDepth() int {
+	return defaultConfirmations
+}
+
+// NextTarget returns the target of the block after parent. Every
+// retargetWindow blocks the target is scaled by how much slower than
+// blockTime the last window of blocks came, limited to maxRetargetFactor.
+func (e *ProofOfWorkEngine) NextTarget(chain HeaderReader, parent *proto.Header) (*big.Int, error) {
+	if parent.Height < retargetWindow || parent.Height%retargetWindow != 0 {
+		return e.target(parent), nil
+	}
+	// genesis is left out, its timestamp is not a mining time
+	first := parent
+	for i := 1; i < retargetWindow; i++ {
+		prev, err := chain.GetHeader(first.PrevHash)
+		if err != nil {
+			return nil, err
+		}
+		first = prev
+	}
+	var (
+		expected = int64(retargetWindow-1) * int64(e.blockTime)
+		actual   = min(max(parent.Timestamp-first.Timestamp, expected/maxRetargetFactor), expected*maxRetargetFactor)
+		target   = new(big.Int).Mul(e.target(parent), big.NewInt(actual))
+	)
+	target.Div(target, big.NewInt(expected))
+	if target.Cmp(maxTarget) > 0 {
+		target.Set(maxTarget)
+	}
+	if target.Sign() == 0 {
+		target.SetInt64(1)
+	}
+	return target, nil
+}
+
+// target returns the target of h, the initial one for headers without,
+// like genesis.
+func (e *ProofOfWorkEngine) target(h *proto.Header) *big.Int {
+	if len(h.Target) == 0 {
+		return e.initialTarget
+	}
+	return new(big.Int).SetBytes(h.Target)
+}
+
+func meetsTarget(hash []byte, target *big.Int) bool {
+	return new(big.Int).SetBytes(hash).Cmp(target) <= 0
+}
+
+// mine searches the nonces for a hash of header meeting target, workers
+// goroutines try every workers-th nonce each.
+func mine(ctx context.Context, header *proto.Header, target *big.Int, workers int) (uint64, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg    sync.WaitGroup
+		found = make(chan uint64, workers)
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(nonce uint64) {
+			defer wg.Done()
+			h := pb.Clone(header).(*proto.Header)
+			for tries := 0; ; tries++ {
+				if tries%nonceBatch == 0 && ctx.Err() != nil {
+					return
+				}
+				h.Nonce = nonce
+				if meetsTarget(types.HashHeader(h), target) {
+					found <- nonce
+					return
+				}
+				nonce += uint64(workers)
+			}
+		}(uint64(i))
+	}
+	go func() {
+		wg.Wait()
+		close(found)
+	}()
+
+	nonce, ok := <-found
+	if !ok {
+		return 0, ctx.Err()
+	}
+	return nonce, nil
+}
@@ -0,0 +1,125 @@
+package node
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/64bitAryan/blocker/crypto"
+	"github.com/64bitAryan/blocker/proto"
+	"github.com/64bitAryan/blocker/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// headerChain serves the headers built by a test.
+type headerChain map[string]*proto.Header
+
+func (c headerChain) GetHeader(hash []byte) (*proto.Header, error) {
+	return c[string(hash)], nil
+}
+
+// add appends a header timestamped dt after parent.
+func (c headerChain) add(t *testing.T, engine *ProofOfWorkEngine, parent *proto.Header, dt time.Duration) *proto.Header {
+	target, err := engine.NextTarget(c, parent)
+	require.Nil(t, err)
+	h := &proto.Header{
+		Height:    parent.Height + 1,
+		PrevHash:  types.HashHeader(parent),
+		Timestamp: parent.Timestamp + int64(dt),
+		Target:    target.Bytes(),
+	}
+	c[string(types.HashHeader(h))] = h
+	return h
+}
+
+func TestMeetsTarget(t *testing.T) {
+	assert.True(t, meetsTarget([]byte{0x00, 0xff}, big.NewInt(0xff)))
+	assert.True(t, meetsTarget([]byte{0x01, 0x00}, big.NewInt(0x100)))
+	assert.False(t, meetsTarget([]byte{0x01, 0x01}, big.NewInt(0x100)))
+}
+
+func TestProofOfWorkSeal(t *testing.T) {
+	var (
+		engine = NewProofOfWorkEngine(8, time.Second)
+		parent = &proto.Header{Timestamp: time.Now().Add(-time.Second).UnixNano()}
+		b      = &proto.Block{
+			Header: &proto.Header{
+				Version:   1,
+				Height:    1,
+				Timestamp: time.Now().UnixNano(),
+			},
+		}
+	)
+	require.Nil(t, engine.Seal(context.Background(), headerChain{}, b, parent, crypto.GeneratePrivateKey()))
+	assert.True(t, types.VerifyBlock(b))
+	assert.Nil(t, engine.VerifySeal(headerChain{}, nil, b, parent, time.Now()))
+	assert.NotNil(t, engine.VerifySeal(headerChain{}, nil, b, b.Header, time.Now()))
+	assert.ErrorContains(t, NewProofOfWorkEngine(4, time.Second).VerifySeal(headerChain{}, nil, b, parent, time.Now()), "target")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, NewProofOfWorkEngine(64, time.Second).Seal(ctx, headerChain{}, b, parent, crypto.GeneratePrivateKey()), context.Canceled)
+}
+
+func TestProofOfWorkRetarget(t *testing.T) {
+	var (
+		engine  = NewProofOfWorkEngine(16, time.Second)
+		chain   = headerChain{}
+		initial = engine.target(&proto.Header{})
+		parent  = chain.add(t, engine, &proto.Header{}, time.Hour)
+	)
+	// the time to the first block is not a mining time
+	for i := 1; i < retargetWindow; i++ {
+		target, err := engine.NextTarget(chain, parent)
+		require.Nil(t, err)
+		assert.Equal(t, initial, target)
+		parent = chain.add(t, engine, parent, time.Second*2)
+	}
+
+	// blocks twice as slow as wanted double the target
+	parent = chain.add(t, engine, parent, time.Second*2)
+	assert.Equal(t, new(big.Int).Lsh(initial, 1), engine.target(parent))
+
+	// fast blocks lower it by at most maxRetargetFactor
+	for i := 1; i < retargetWindow; i++ {
+		parent = chain.add(t, engine, parent, time.Nanosecond)
+	}
+	parent = chain.add(t, engine, parent, time.Nanosecond)
+	assert.Equal(t, new(big.Int).Rsh(initial, 1), engine.target(parent))
+}
+
+func TestProofOfWorkWork(t *testing.T) {
+	engine := NewProofOfWorkEngine(8, time.Second)
+	assert.Equal(t, int64(256), engine.Work(&proto.Header{}).Int64())
+	h := &proto.Header{Target: new(big.Int).Rsh(maxTarget, 10).Bytes()}
+	assert.Equal(t, int64(1024), engine.Work(h).Int64())
+}
+
+func TestProofOfWorkChain(t *testing.T) {
+	n := NewNode(ServerConfig{
+		PrivateKey: crypto.GeneratePrivateKey(),
+		Engine:     NewProofOfWorkEngine(8, time.Millisecond),
+	})
+	for i := 0; i < defaultConfirmations+2; i++ {
+		require.Nil(t, n.produceBlock(nil))
+	}
+	assert.Equal(t, 2, n.chain.FinalizedHeight())
+	assert.True(t, n.chain.TotalWork().Cmp(big.NewInt((defaultConfirmations+2)*256/maxRetargetFactor)) >= 0)
+
+	// blocks without the work are rejected
+	var (
+		engine      = n.chain.Engine().(*ProofOfWorkEngine)
+		b           = randomBlock(t, n.chain)
+		target, err = engine.NextTarget(n.chain, n.chain.headers.Get(n.chain.Height()))
+	)
+	require.Nil(t, err)
+	b.Header.Target = target.Bytes()
+	types.SignBlock(n.PrivateKey, b)
+	for meetsTarget(types.HashBlock(b), target) {
+		b.Header.Nonce++
+		types.SignBlock(n.PrivateKey, b)
+	}
+	assert.ErrorContains(t, n.chain.AddBlock(b), "does not meet")
+}
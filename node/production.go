@@ -0,0 +1,49 @@
+package node
+
+// ProductionMode decides when validators seal blocks.
+type ProductionMode int
+
+const (
+	// ProduceSteady seals a block in every slot, empty or not
+	ProduceSteady ProductionMode = iota
+	// ProduceNonEmpty leaves the slots out while the mempool is empty
+	ProduceNonEmpty
+	// ProduceOnDemand is ProduceNonEmpty that also seals a block as soon
+	// as the mempool holds MinTxs transactions or MinBytes bytes
+	ProduceOnDemand
+	// ProduceInstamine seals a block as soon as a transaction arrives, for
+	// development chains
+	ProduceInstamine
+)
+
+// ProductionPolicy tells validators when to seal blocks. Blocks sealed on
+// demand still follow the engine, with a validator set only in our own
+// slots.
+type ProductionPolicy struct {
+	Mode ProductionMode
+	// thresholds of ProduceOnDemand, 0 disables one
+	MinTxs   int
+	MinBytes int
+}
+
+// onDemand reports whether blocks may be due before their slot.
+func (p ProductionPolicy) onDemand() bool {
+	return p.Mode == ProduceOnDemand || p.Mode == ProduceInstamine
+}
+
+// skip reports whether a slot is left out with txs pending.
+func (p ProductionPolicy) skip(txs int) bool {
+	return p.Mode != ProduceSteady && txs == 0
+}
+
+// due reports whether a block is due right away with txs of size bytes
+// pending.
+func (p ProductionPolicy) due(txs int, size int) bool {
+	switch p.Mode {
+	case ProduceOnDemand:
+		return (p.MinTxs > 0 && txs >= p.MinTxs) || (p.MinBytes > 0 && size >= p.MinBytes)
+	case ProduceInstamine:
+		return txs > 0
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package node
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/64bitAryan/blocker/crypto"
+	"github.com/64bitAryan/blocker/proto"
+	"github.com/64bitAryan/blocker/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProductionPolicy(t *testing.T) {
+	steady := ProductionPolicy{}
+	assert.False(t, steady.onDemand())
+	assert.False(t, steady.skip(0))
+	assert.False(t, steady.due(100, 1<<20))
+
+	nonEmpty := ProductionPolicy{Mode: ProduceNonEmpty}
+	assert.True(t, nonEmpty.skip(0))
+	assert.False(t, nonEmpty.skip(1))
+	assert.False(t, nonEmpty.due(100, 1<<20))
+
+	onDemand := ProductionPolicy{Mode: ProduceOnDemand, MinTxs: 10, MinBytes: 1000}
+	assert.True(t, onDemand.onDemand())
+	assert.True(t, onDemand.skip(0))
+	assert.False(t, onDemand.due(9, 999))
+	assert.True(t, onDemand.due(10, 0))
+	assert.True(t, onDemand.due(1, 1000))
+
+	instamine := ProductionPolicy{Mode: ProduceInstamine}
+	assert.False(t, instamine.due(0, 0))
+	assert.True(t, instamine.due(1, 0))
+}
+
+// runValidator runs the validator loop of n until the test ends.
+func runValidator(t *testing.T, n *Node) {
+	done := make(chan struct{})
+	go func() {
+		n.validatorLoop()
+		close(done)
+	}()
+	t.Cleanup(func() {
+		n.cancel()
+		<-done
+	})
+}
+
+// genesisSpend returns a transaction spending the development genesis.
+func genesisSpend(t *testing.T, chain *Chain) *proto.Transaction {
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	privKey := GenesisKey()
+	tx := &proto.Transaction{
+		Version: 1,
+		Inputs: []*proto.TxInput{{
+			PrevTxHash: types.HashTransaction(genesis.Transactions[0]),
+			PublicKey:  privKey.Public().Bytes(),
+		}},
+		Outputs: []*proto.TxOutput{{
+			Amount:  1000,
+			Address: crypto.GeneratePrivateKey().Public().Address().Bytes(),
+		}},
+	}
+	tx.Inputs[0].Signature = types.SignTransaction(privKey, tx).Bytes()
+	return tx
+}
+
+func TestProduceNonEmpty(t *testing.T) {
+	n := NewNode(ServerConfig{
+		PrivateKey: crypto.GeneratePrivateKey(),
+		Params:     ChainParams{BlockTime: time.Millisecond * 10},
+		Production: ProductionPolicy{Mode: ProduceNonEmpty},
+	})
+	runValidator(t, n)
+	time.Sleep(time.Millisecond * 50)
+	assert.Equal(t, 0, n.Height())
+
+	_, err := n.HandleTransaction(context.Background(), genesisSpend(t, n.chain))
+	require.Nil(t, err)
+	assert.Eventually(t, func() bool { return n.Height() == 1 }, time.Second, time.Millisecond*5)
+	time.Sleep(time.Millisecond * 50)
+	assert.Equal(t, 1, n.Height())
+}
+
+func TestProduceInstamine(t *testing.T) {
+	n := NewNode(ServerConfig{
+		PrivateKey: crypto.GeneratePrivateKey(),
+		Params:     ChainParams{BlockTime: time.Hour},
+		Production: ProductionPolicy{Mode: ProduceInstamine},
+	})
+	runValidator(t, n)
+
+	_, err := n.HandleTransaction(context.Background(), genesisSpend(t, n.chain))
+	require.Nil(t, err)
+	assert.Eventually(t, func() bool { return n.Height() == 1 }, time.Second, time.Millisecond*5)
+}
+
+func TestProduceBlockDropsConflicts(t *testing.T) {
+	n := NewNode(ServerConfig{
+		PrivateKey: crypto.GeneratePrivateKey(),
+		Params:     ChainParams{BlockTime: time.Hour},
+	})
+	first, second := genesisSpend(t, n.chain), genesisSpend(t, n.chain)
+	require.Nil(t, n.produceBlock([]*proto.Transaction{first, second}))
+
+	b, err := n.chain.GetBlockByHeight(1)
+	require.Nil(t, err)
+	require.Len(t, b.Transactions, 1)
+	assert.Equal(t, types.HashTransaction(first), types.HashTransaction(b.Transactions[0]))
+}
@@ -0,0 +1,170 @@
+package node
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/64bitAryan/blocker/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// largest message accepted or sent on a connection
+	maxMessageSize = 4 << 20
+	// most concurrent calls on a single connection
+	maxConcurrentStreams = 64
+	// idle buckets are dropped once this many hosts are tracked
+	maxRateBuckets = 10_000
+)
+
+// RateLimit allows Burst calls at once, refilled at Rate calls per second.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// times is the limit shared by k peers.
+func (l RateLimit) times(k int) RateLimit {
+	return RateLimit{Rate: l.Rate * float64(k), Burst: l.Burst * k}
+}
+
+// the limit of every RPC not listed in methodLimits, including the ones
+// added later
+var defaultLimit = RateLimit{Rate: 10, Burst: 20}
+
+var methodLimits = map[string]RateLimit{
+	proto.Node_Handshake_FullMethodName:         {Rate: 1, Burst: 5},
+	proto.Node_GetChallenge_FullMethodName:      {Rate: 1, Burst: 5},
+	proto.Node_Identify_FullMethodName:          {Rate: 1, Burst: 5},
+	proto.Node_HandleTransaction_FullMethodName: {Rate: 50, Burst: 100},
+	proto.Node_Announce_FullMethodName:          {Rate: 100, Burst: 200},
+	proto.Node_GetData_FullMethodName:           {Rate: 50, Burst: 100},
+	proto.Node_HandleVote_FullMethodName:        {Rate: 100, Burst: 200},
+	proto.Node_HandleEvidence_FullMethodName:    {Rate: 10, Burst: 20},
+	proto.Node_GetBlocks_FullMethodName:         {Rate: 2, Burst: 10},
+	proto.Node_Heartbeat_FullMethodName:         {Rate: 1, Burst: 5},
+	proto.Node_GetAddr_FullMethodName:           {Rate: 0.1, Burst: 2},
+}
+
+type tokenBucket struct {
+	limit  RateLimit
+	tokens float64
+	last   time.Time
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * b.limit.Rate
+	b.tokens = min(b.tokens, float64(b.limit.Burst))
+	b.last = now
+}
+
+func (b *tokenBucket) allow(now time.Time) bool {
+	b.refill(now)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// rateLimiter keeps a token bucket for every host and method, shared by
+// the peers behind the host.
+type rateLimiter struct {
+	lock    sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+func (l *rateLimiter) allow(host string, method string, peers int, now time.Time) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	limit, ok := methodLimits[method]
+	if !ok {
+		limit = defaultLimit
+	}
+	limit = limit.times(max(peers, 1))
+
+	key := host + method
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxRateBuckets {
+			l.sweep(now)
+		}
+		b = &tokenBucket{
+			limit:  limit,
+			tokens: float64(limit.Burst),
+			last:   now,
+		}
+		l.buckets[key] = b
+	}
+	// the limit follows the number of peers behind the host, joining peers
+	// bring their own burst
+	b.refill(now)
+	b.tokens += float64(max(limit.Burst-b.limit.Burst, 0))
+	b.limit = limit
+	return b.allow(now)
+}
+
+// sweep drops the buckets that refilled completely, they behave exactly
+// like new ones.
+func (l *rateLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// rateLimitInterceptor rejects the calls of hosts exceeding the limit of
+// the method.
+func (n *Node) rateLimitInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	host := remoteHost(ctx)
+	if !n.limiter.allow(host, info.FullMethod, n.peersOnHost(host), time.Now()) {
+		n.clientMisbehaving(ctx, penaltyRateLimit, "rate limit exceeded")
+		return nil, status.Errorf(codes.ResourceExhausted, "rate limit of %s exceeded", info.FullMethod)
+	}
+	return handler(ctx, req)
+}
+
+// peersOnHost counts the peers listening on host.
+func (n *Node) peersOnHost(host string) int {
+	n.peerLock.RLock()
+	defer n.peerLock.RUnlock()
+
+	count := 0
+	for addr := range n.peers {
+		if h, _, err := net.SplitHostPort(addr); err == nil && h == host {
+			count++
+		}
+	}
+	return count
+}
+
+func (n *Node) limitOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.MaxRecvMsgSize(maxMessageSize),
+		grpc.MaxSendMsgSize(maxMessageSize),
+		grpc.MaxConcurrentStreams(maxConcurrentStreams),
+		grpc.ChainUnaryInterceptor(n.rateLimitInterceptor),
+	}
+}
+
+func dialLimitOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithDefaultCallOptions(
+			grpc.MaxCallRecvMsgSize(maxMessageSize),
+			grpc.MaxCallSendMsgSize(maxMessageSize),
+		),
+	}
+}
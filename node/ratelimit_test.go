@@ -0,0 +1,143 @@
+package node
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/64bitAryan/blocker/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcpeer "google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestTokenBucket(t *testing.T) {
+	var (
+		now = time.Now()
+		b   = &tokenBucket{
+			limit:  RateLimit{Rate: 2, Burst: 4},
+			tokens: 4,
+			last:   now,
+		}
+	)
+	for i := 0; i < 4; i++ {
+		assert.True(t, b.allow(now))
+	}
+	assert.False(t, b.allow(now))
+	assert.True(t, b.allow(now.Add(time.Millisecond*500)))
+	assert.False(t, b.allow(now.Add(time.Millisecond*500)))
+
+	// never refills beyond the burst
+	later := now.Add(time.Hour)
+	for i := 0; i < 4; i++ {
+		assert.True(t, b.allow(later))
+	}
+	assert.False(t, b.allow(later))
+}
+
+func TestRateLimiterPerHost(t *testing.T) {
+	var (
+		l      = newRateLimiter()
+		now    = time.Now()
+		method = proto.Node_Handshake_FullMethodName
+		limit  = methodLimits[method]
+	)
+	for i := 0; i < limit.Burst; i++ {
+		assert.True(t, l.allow("10.0.0.1", method, 1, now))
+	}
+	assert.False(t, l.allow("10.0.0.1", method, 1, now))
+	// other hosts and methods have their own buckets
+	assert.True(t, l.allow("10.0.0.2", method, 1, now))
+	assert.True(t, l.allow("10.0.0.1", proto.Node_HandleTransaction_FullMethodName, 1, now))
+
+	// unknown methods get the default limit
+	for i := 0; i < defaultLimit.Burst; i++ {
+		assert.True(t, l.allow("10.0.0.1", "/Node/Unknown", 1, now))
+	}
+	assert.False(t, l.allow("10.0.0.1", "/Node/Unknown", 1, now))
+
+	l.sweep(now.Add(time.Hour))
+	assert.Empty(t, l.buckets)
+}
+
+func TestRateLimiterSharedHost(t *testing.T) {
+	var (
+		l      = newRateLimiter()
+		now    = time.Now()
+		method = proto.Node_Heartbeat_FullMethodName
+		limit  = methodLimits[method]
+	)
+	// three peers behind one address each get the burst of a single host
+	for i := 0; i < 3*limit.Burst; i++ {
+		assert.True(t, l.allow("10.0.0.1", method, 3, now))
+	}
+	assert.False(t, l.allow("10.0.0.1", method, 3, now))
+	assert.True(t, l.allow("10.0.0.1", method, 3, now.Add(time.Second/2)))
+
+	// and the limit shrinks back once they leave
+	later := now.Add(time.Hour)
+	for i := 0; i < limit.Burst; i++ {
+		assert.True(t, l.allow("10.0.0.1", method, 1, later))
+	}
+	assert.False(t, l.allow("10.0.0.1", method, 1, later))
+}
+
+func TestRateLimitInterceptor(t *testing.T) {
+	var (
+		n   = NewNode(ServerConfig{ListenAddr: ":3000"})
+		ctx = grpcpeer.NewContext(context.Background(), &grpcpeer.Peer{
+			Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 50000},
+		})
+		info    = &grpc.UnaryServerInfo{FullMethod: proto.Node_GetAddr_FullMethodName}
+		handler = func(ctx context.Context, req any) (any, error) {
+			return &proto.AddrList{}, nil
+		}
+	)
+	for i := 0; i < methodLimits[info.FullMethod].Burst; i++ {
+		_, err := n.rateLimitInterceptor(ctx, &proto.AddrRequest{}, info, handler)
+		require.Nil(t, err)
+	}
+	_, err := n.rateLimitInterceptor(ctx, &proto.AddrRequest{}, info, handler)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, penaltyRateLimit, n.bans.Score("10.0.0.1", time.Now()))
+
+	// a second peer on the host brings its own burst
+	addFakePeer(n, "10.0.0.1:4000", &fakeNodeClient{alive: true})
+	addFakePeer(n, "10.0.0.1:5000", &fakeNodeClient{alive: true})
+	for i := 0; i < methodLimits[info.FullMethod].Burst; i++ {
+		_, err := n.rateLimitInterceptor(ctx, &proto.AddrRequest{}, info, handler)
+		require.Nil(t, err)
+	}
+	_, err = n.rateLimitInterceptor(ctx, &proto.AddrRequest{}, info, handler)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestMaxMessageSize(t *testing.T) {
+	var (
+		addr = freeAddr(t)
+		n    = NewNode(ServerConfig{})
+	)
+	go n.Start(context.Background(), addr, []string{})
+	defer n.Stop()
+	waitForListener(t, addr)
+
+	p, err := newPeer(addr, grpc.WithInsecure())
+	require.Nil(t, err)
+	defer p.close()
+
+	_, err = p.HandleTransaction(context.Background(), &proto.Transaction{Version: 42})
+	require.Nil(t, err)
+
+	tx := &proto.Transaction{
+		Version: 42,
+		Outputs: []*proto.TxOutput{
+			{LockScript: make([]byte, maxMessageSize)},
+		},
+	}
+	_, err = p.HandleTransaction(context.Background(), tx)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
@@ -3,6 +3,7 @@ package node
 import (
 	"encoding/hex"
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/64bitAryan/blocker/proto"
@@ -12,16 +13,21 @@ import (
 type UTXOStorer interface {
 	Put(*UTXO) error
 	Get(string) (*UTXO, error)
+	// ListUnspent returns the unspent outputs of the hex address
+	ListUnspent(address string) ([]*UTXO, error)
 }
 
 type MemoryUTXOStore struct {
 	lock sync.RWMutex
 	data map[string]*UTXO
+	// keys of the unspent outputs by hex address
+	unspent map[string]map[string]bool
 }
 
 func NewMemoryUTXOStore() *MemoryUTXOStore {
 	return &MemoryUTXOStore{
-		data: make(map[string]*UTXO),
+		data:    make(map[string]*UTXO),
+		unspent: make(map[string]map[string]bool),
 	}
 }
 
@@ -41,9 +47,42 @@ func (s *MemoryUTXOStore) Put(utxo *UTXO) error {
 	key := fmt.Sprintf("%s-%d", utxo.Hash, utxo.OutIndex)
 	s.data[key] = utxo
 
+	if len(utxo.Address) == 0 {
+		return nil
+	}
+	keys, ok := s.unspent[utxo.Address]
+	if !ok {
+		keys = make(map[string]bool)
+		s.unspent[utxo.Address] = keys
+	}
+	if utxo.Spent {
+		delete(keys, key)
+	} else {
+		keys[key] = true
+	}
+	if len(keys) == 0 {
+		delete(s.unspent, utxo.Address)
+	}
 	return nil
 }
 
+// ListUnspent returns the outputs ordered by transaction hash and index.
+func (s *MemoryUTXOStore) ListUnspent(address string) ([]*UTXO, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	utxos := []*UTXO{}
+	for key := range s.unspent[address] {
+		utxos = append(utxos, s.data[key])
+	}
+	sort.Slice(utxos, func(i, j int) bool {
+		if utxos[i].Hash != utxos[j].Hash {
+			return utxos[i].Hash < utxos[j].Hash
+		}
+		return utxos[i].OutIndex < utxos[j].OutIndex
+	})
+	return utxos, nil
+}
+
 type TXStorer interface {
 	Put(*proto.Transaction) error
 	Get(string) (*proto.Transaction, error)
@@ -78,19 +117,25 @@ func (s *MemoryTXStore) Put(tx *proto.Transaction) error {
 	return nil
 }
 
+// BlockStorer stores blocks and the commit certificates of the final ones
+// by block hash.
 type BlockStorer interface {
 	Put(*proto.Block) error
 	Get(string) (*proto.Block, error)
+	PutCommit(*proto.Commit) error
+	GetCommit(string) (*proto.Commit, error)
 }
 
 type MemoryBlockStore struct {
-	lock  sync.RWMutex
-	block map[string]*proto.Block
+	lock    sync.RWMutex
+	block   map[string]*proto.Block
+	commits map[string]*proto.Commit
 }
 
 func NewMemoryBlockStore() *MemoryBlockStore {
 	return &MemoryBlockStore{
-		block: make(map[string]*proto.Block),
+		block:   make(map[string]*proto.Block),
+		commits: make(map[string]*proto.Commit),
 	}
 }
 
@@ -111,3 +156,20 @@ func (s *MemoryBlockStore) Get(hash string) (*proto.Block, error) {
 	}
 	return block, nil
 }
+
+func (s *MemoryBlockStore) PutCommit(commit *proto.Commit) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.commits[hex.EncodeToString(commit.BlockHash)] = commit
+	return nil
+}
+
+func (s *MemoryBlockStore) GetCommit(hash string) (*proto.Commit, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	commit, ok := s.commits[hash]
+	if !ok {
+		return nil, fmt.Errorf("no commit for block [%s]", hash)
+	}
+	return commit, nil
+}
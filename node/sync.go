@@ -0,0 +1,167 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/64bitAryan/blocker/proto"
+	"github.com/64bitAryan/blocker/types"
+	pb "google.golang.org/protobuf/proto"
+)
+
+const (
+	// most blocks returned for a locator
+	maxSyncBlocks = 500
+	// most hashes of a locator, enough for any height
+	maxLocatorHashes = 100
+	// validators wait at most this long for a sync before producing anyway
+	maxSyncWait = time.Minute
+)
+
+// Locator returns hashes of the canonical chain from the tip down to the
+// genesis, every block of the last ten and exponentially fewer below.
+func (c *Chain) Locator() [][]byte {
+	var (
+		hashes = [][]byte{}
+		step   = 1
+	)
+	for height := c.Height(); height > 0; height -= step {
+		if h, ok := c.headers.Lookup(height); ok {
+			hashes = append(hashes, types.HashHeader(h))
+		}
+		if len(hashes) >= 10 {
+			step *= 2
+		}
+	}
+	return append(hashes, c.GenesisHash())
+}
+
+// BlocksAfter returns the canonical blocks following the first block of
+// locator that is on the canonical chain, at most max of them taking
+// about maxBytes.
+func (c *Chain) BlocksAfter(locator [][]byte, max int, maxBytes int) ([]*proto.Block, error) {
+	start := -1
+	for _, hash := range locator {
+		b, err := c.GetBlockByHash(hash)
+		if err == nil && c.isCanonical(b.Header) {
+			start = int(b.Header.Height)
+			break
+		}
+	}
+	if start < 0 {
+		return nil, fmt.Errorf("locator shares no block with our chain")
+	}
+	var (
+		blocks = []*proto.Block{}
+		size   = 0
+	)
+	for height := start + 1; len(blocks) < max; height++ {
+		b, err := c.GetBlockByHeight(height)
+		if err != nil {
+			break
+		}
+		if size += pb.Size(b); size > maxBytes && len(blocks) > 0 {
+			break
+		}
+		blocks = append(blocks, b)
+	}
+	return blocks, nil
+}
+
+func (n *Node) GetBlocks(ctx context.Context, loc *proto.Locator) (*proto.Items, error) {
+	if len(loc.Hashes) > maxLocatorHashes {
+		p, _ := n.senderPeer(ctx, loc.ListenAddr)
+		n.senderMisbehaving(ctx, p, penaltySpam, "oversized locator")
+		return nil, fmt.Errorf("locator of %d hashes exceeds limit (%d)", len(loc.Hashes), maxLocatorHashes)
+	}
+	blocks, err := n.chain.BlocksAfter(loc.Hashes, maxSyncBlocks, maxMessageSize/2)
+	if err != nil {
+		return nil, err
+	}
+	return &proto.Items{Blocks: blocks}, nil
+}
+
+// syncBlocks fetches the blocks we are missing to connect the orphan block
+// b from p, in batches following a locator of our chain, and adds them in
+// order. The chain switches to their branch when it has more work than
+// ours, which a shorter branch may have too.
+func (n *Node) syncBlocks(p *peer, b *proto.Block) error {
+	if int(b.Header.Height) <= n.chain.FinalizedHeight() {
+		return nil
+	}
+	if !n.syncing.CompareAndSwap(false, true) {
+		return nil
+	}
+	defer n.syncing.Store(false)
+
+	var (
+		hash    = types.HashBlock(b)
+		locator = n.chain.Locator()
+		synced  = 0
+	)
+	for !n.chain.HasBlock(hash) {
+		ctx, cancel := context.WithTimeout(n.ctx, requestTimeout)
+		resp, err := p.GetBlocks(ctx, &proto.Locator{
+			Hashes:     locator,
+			ListenAddr: n.ListenAddr,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		if len(resp.Blocks) == 0 {
+			n.misbehaving(p.version.ListenAddr, penaltySpam, "missing announced ancestor")
+			return fmt.Errorf("peer did not return the ancestors of block %x", hash)
+		}
+		added := 0
+		for _, b := range resp.Blocks {
+			if n.chain.HasBlock(types.HashBlock(b)) {
+				continue
+			}
+			if err := n.processBlock(b); err != nil {
+				n.misbehaving(p.version.ListenAddr, penaltyInvalidBlock, err.Error())
+				return err
+			}
+			p.seenHeight(int64(b.Header.Height))
+			added++
+		}
+		if added == 0 {
+			n.misbehaving(p.version.ListenAddr, penaltySpam, "no new blocks for locator")
+			return fmt.Errorf("peer returned no new blocks")
+		}
+		synced += added
+		// the next batch follows the last block, which may be on a branch
+		// our locator does not know
+		last := types.HashBlock(resp.Blocks[len(resp.Blocks)-1])
+		locator = append([][]byte{last}, n.chain.Locator()...)
+	}
+	n.logger.Debugw("synced blocks", "we", n.ListenAddr, "remote", p.version.ListenAddr, "blocks", synced, "height", n.chain.Height())
+	return nil
+}
+
+// synced reports whether we are connected and no peer is known to be ahead
+// of us, validators of a set only propose blocks when they are.
+func (n *Node) synced() bool {
+	n.peerLock.RLock()
+	connected := len(n.peers) > 0
+	n.peerLock.RUnlock()
+	return connected && !n.behind()
+}
+
+// behind reports whether we are syncing, or a peer sent us valid blocks
+// above our tip. The heights peers claim are not trusted for it.
+func (n *Node) behind() bool {
+	if n.syncing.Load() {
+		return true
+	}
+	n.peerLock.RLock()
+	defer n.peerLock.RUnlock()
+	height := int64(n.chain.Height())
+	for _, p := range n.peers {
+		if p.height.Load() > height {
+			return true
+		}
+	}
+	return false
+}
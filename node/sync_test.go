@@ -0,0 +1,98 @@
+package node
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/64bitAryan/blocker/crypto"
+	"github.com/64bitAryan/blocker/proto"
+	"github.com/64bitAryan/blocker/types"
+	"github.com/64bitAryan/blocker/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncMissingBlocks(t *testing.T) {
+	var (
+		validator = NewNode(ServerConfig{ListenAddr: ":3000", PrivateKey: crypto.GeneratePrivateKey()})
+		follower  = NewNode(ServerConfig{ListenAddr: ":4000"})
+	)
+	for i := 0; i < 5; i++ {
+		require.Nil(t, validator.produceBlock([]*proto.Transaction{}))
+	}
+	addFakePeer(follower, ":3000", &fakeNodeClient{alive: true, source: validator})
+	defer follower.deletePeer(":3000")
+	p := follower.getPeer(":3000")
+	assert.True(t, follower.synced())
+
+	tip, err := validator.chain.GetBlockByHeight(5)
+	require.Nil(t, err)
+	follower.fetch(p, []*proto.InvItem{blockInvItem(tip)})
+
+	assert.Equal(t, 5, follower.chain.Height())
+	assert.Equal(t, int64(5), p.height.Load())
+	assert.True(t, follower.synced())
+	assert.Zero(t, follower.bans.Score(":3000", time.Now()))
+}
+
+func TestNotSyncedBehindPeers(t *testing.T) {
+	n := NewNode(ServerConfig{ListenAddr: ":3000"})
+	// without peers we can't know
+	assert.False(t, n.synced())
+
+	// the height a peer claims proves nothing
+	n.addPeer(newPeerFromClient(&fakeNodeClient{alive: true}), &proto.Version{ListenAddr: ":4000", Height: math.MaxInt32})
+	defer n.deletePeer(":4000")
+	assert.True(t, n.synced())
+
+	// valid blocks above our tip do
+	n.getPeer(":4000").seenHeight(3)
+	assert.False(t, n.synced())
+}
+
+func TestSyncManyBlocks(t *testing.T) {
+	var (
+		validator = NewNode(ServerConfig{ListenAddr: ":3000", PrivateKey: crypto.GeneratePrivateKey()})
+		follower  = NewNode(ServerConfig{ListenAddr: ":4000"})
+		height    = 2*maxSyncBlocks + 10
+	)
+	for i := 0; i < height; i++ {
+		require.Nil(t, validator.produceBlock([]*proto.Transaction{}))
+	}
+	addFakePeer(follower, ":3000", &fakeNodeClient{alive: true, source: validator})
+	defer follower.deletePeer(":3000")
+	p := follower.getPeer(":3000")
+
+	tip, err := validator.chain.GetBlockByHeight(height)
+	require.Nil(t, err)
+	follower.fetch(p, []*proto.InvItem{blockInvItem(tip)})
+
+	assert.Equal(t, height, follower.chain.Height())
+	assert.False(t, follower.behind())
+	assert.Zero(t, follower.bans.Score(":3000", time.Now()))
+}
+
+func TestBlocksAfter(t *testing.T) {
+	n := NewNode(ServerConfig{PrivateKey: crypto.GeneratePrivateKey()})
+	for i := 0; i < 40; i++ {
+		require.Nil(t, n.produceBlock([]*proto.Transaction{}))
+	}
+	locator := n.chain.Locator()
+	assert.Less(t, len(locator), 20)
+	assert.Equal(t, n.chain.GenesisHash(), locator[len(locator)-1])
+
+	b, err := n.chain.GetBlockByHeight(30)
+	require.Nil(t, err)
+	blocks, err := n.chain.BlocksAfter([][]byte{util.RandomHash(), types.HashBlock(b)}, 5, maxMessageSize)
+	require.Nil(t, err)
+	require.Len(t, blocks, 5)
+	assert.Equal(t, int32(31), blocks[0].Header.Height)
+
+	blocks, err = n.chain.BlocksAfter(locator, maxSyncBlocks, maxMessageSize)
+	require.Nil(t, err)
+	assert.Empty(t, blocks)
+
+	_, err = n.chain.BlocksAfter([][]byte{util.RandomHash()}, maxSyncBlocks, maxMessageSize)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,91 @@
+package node
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/64bitAryan/blocker/crypto"
+	"github.com/64bitAryan/blocker/proto"
+)
+
+// ValidatorSet schedules block production in proof of authority: time is
+// cut into slots of slotDuration and the validators take turns proposing
+// one block per slot. A validator that is offline only costs its own slots.
+type ValidatorSet struct {
+	validators   []*crypto.PublicKeys
+	slotDuration time.Duration
+}
+
+func NewValidatorSet(slotDuration time.Duration, validators ...*crypto.PublicKeys) *ValidatorSet {
+	if slotDuration <= 0 {
+		panic("slot duration must be positive")
+	}
+	return &ValidatorSet{
+		validators:   validators,
+		slotDuration: slotDuration,
+	}
+}
+
+func (vs *ValidatorSet) Len() int {
+	return len(vs.validators)
+}
+
+func (vs *ValidatorSet) Validators() []*crypto.PublicKeys {
+	return append([]*crypto.PublicKeys{}, vs.validators...)
+}
+
+func (vs *ValidatorSet) SlotDuration() time.Duration {
+	return vs.slotDuration
+}
+
+func (vs *ValidatorSet) Contains(pubKey *crypto.PublicKeys) bool {
+	for _, validator := range vs.validators {
+		if bytes.Equal(validator.Bytes(), pubKey.Bytes()) {
+			return true
+		}
+	}
+	return false
+}
+
+// quorum reports whether n validators are more than two thirds of the set.
+func (vs *ValidatorSet) quorum(n int) bool {
+	return n*3 > len(vs.validators)*2
+}
+
+// Slot returns the slot of the timestamp (unix nano).
+func (vs *ValidatorSet) Slot(timestamp int64) int64 {
+	return timestamp / int64(vs.slotDuration)
+}
+
+func (vs *ValidatorSet) SlotStart(slot int64) time.Time {
+	return time.Unix(0, slot*int64(vs.slotDuration))
+}
+
+// Proposer returns the validator scheduled for slot, nil for an empty set.
+func (vs *ValidatorSet) Proposer(slot int64) *crypto.PublicKeys {
+	if len(vs.validators) == 0 {
+		return nil
+	}
+	return vs.validators[slot%int64(len(vs.validators))]
+}
+
+// validateProposer checks that b is signed by the validator of its slot and
+// comes in a later slot than its parent.
+func (vs *ValidatorSet) validateProposer(b *proto.Block, parent *proto.Header, now time.Time) error {
+	var (
+		slot       = vs.Slot(b.Header.Timestamp)
+		parentSlot = vs.Slot(parent.Timestamp)
+	)
+	if slot <= parentSlot {
+		return fmt.Errorf("block slot (%d) does not follow parent slot (%d)", slot, parentSlot)
+	}
+	if slot > vs.Slot(now.UnixNano())+1 {
+		return fmt.Errorf("block slot (%d) is in the future", slot)
+	}
+	proposer := vs.Proposer(slot)
+	if proposer == nil || !bytes.Equal(b.PublicKey, proposer.Bytes()) {
+		return fmt.Errorf("block of slot %d is not signed by the scheduled validator", slot)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/64bitAryan/blocker/crypto"
+	"github.com/64bitAryan/blocker/proto"
+	"github.com/64bitAryan/blocker/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func validatorKeys(n int) ([]*crypto.PrivateKeys, []*crypto.PublicKeys) {
+	var (
+		privKeys = []*crypto.PrivateKeys{}
+		pubKeys  = []*crypto.PublicKeys{}
+	)
+	for i := 0; i < n; i++ {
+		privKey := crypto.GeneratePrivateKey()
+		privKeys = append(privKeys, privKey)
+		pubKeys = append(pubKeys, privKey.Public())
+	}
+	return privKeys, pubKeys
+}
+
+// slotBlock returns the next block of chain in slot with txx, signed by
+// privKey.
+func slotBlock(t *testing.T, chain *Chain, slot int64, privKey *crypto.PrivateKeys, txx ...*proto.Transaction) *proto.Block {
+	prevBlock, err := chain.GetBlockByHeight(chain.Height())
+	require.Nil(t, err)
+	b := &proto.Block{
+		Header: &proto.Header{
+			Version:   1,
+			Height:    int32(chain.Height() + 1),
+			PrevHash:  types.HashBlock(prevBlock),
+			Timestamp: chain.ValidatorSet().SlotStart(slot).UnixNano(),
+		},
+		Transactions: txx,
+	}
+	types.SignBlock(privKey, b)
+	return b
+}
+
+func TestValidatorSetSchedule(t *testing.T) {
+	_, pubKeys := validatorKeys(3)
+	vs := NewValidatorSet(time.Second, pubKeys...)
+
+	assert.Equal(t, int64(5), vs.Slot(int64(time.Second*5+time.Millisecond)))
+	assert.Equal(t, time.Unix(5, 0), vs.SlotStart(5))
+	for slot := int64(0); slot < 6; slot++ {
+		assert.Equal(t, pubKeys[slot%3], vs.Proposer(slot))
+	}
+	assert.True(t, vs.Contains(pubKeys[1]))
+	assert.False(t, vs.Contains(crypto.GeneratePrivateKey().Public()))
+	assert.Nil(t, NewValidatorSet(time.Second).Proposer(1))
+}
+
+func TestValidateBlockProposer(t *testing.T) {
+	var (
+		privKeys, pubKeys = validatorKeys(3)
+		chain             = NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+		vs                = NewValidatorSet(time.Second, pubKeys...)
+		now               = vs.Slot(time.Now().UnixNano())
+	)
+	chain.SetValidatorSet(vs)
+
+	// signed by a key outside the set or another validator
+	assert.NotNil(t, chain.AddBlock(slotBlock(t, chain, now, crypto.GeneratePrivateKey())))
+	assert.NotNil(t, chain.AddBlock(slotBlock(t, chain, now, privKeys[(now+1)%3])))
+
+	require.Nil(t, chain.AddBlock(slotBlock(t, chain, now, privKeys[now%3])))
+	// one block per slot
+	assert.NotNil(t, chain.AddBlock(slotBlock(t, chain, now, privKeys[now%3])))
+	// slots too far ahead of our clock
+	assert.NotNil(t, chain.AddBlock(slotBlock(t, chain, now+10, privKeys[(now+10)%3])))
+
+	require.Nil(t, chain.AddBlock(slotBlock(t, chain, now+1, privKeys[(now+1)%3])))
+	assert.Equal(t, 2, chain.Height())
+}
+
+func TestValidatorLoopOutsideSet(t *testing.T) {
+	_, pubKeys := validatorKeys(2)
+	n := NewNode(ServerConfig{
+		PrivateKey: crypto.GeneratePrivateKey(),
+		Validators: pubKeys,
+		Params:     ChainParams{BlockTime: time.Millisecond * 10},
+	})
+	done := make(chan struct{})
+	go func() {
+		n.validatorLoop()
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 50)
+	n.cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("validator loop kept running")
+	}
+	assert.Equal(t, 0, n.Height())
+}
@@ -0,0 +1,82 @@
+package node
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/64bitAryan/blocker/crypto"
+	"github.com/64bitAryan/blocker/proto"
+)
+
+const (
+	// blocks with fewer transactions are verified one signature at a time
+	batchVerifyMinTxs = 16
+	// number of transactions whose signatures share a batch
+	batchVerifySize = 64
+)
+
+type txBatch struct {
+	txx      []*proto.Transaction
+	verifier *crypto.BatchVerifier
+	// the batch holds an invalid signature
+	failed bool
+}
+
+// validateTransactionsBatch runs the scripts of all transactions assuming
+// every signature check succeeds, then verifies the collected signatures
+// in batches on a pool of workers. When a batch fails, or a transaction
+// fails under that assumption, the scripts of the block run again with
+// every signature checked right away, which finds the culprit and its exact
+// error.
+func (c *Chain) validateTransactionsBatch(txx []*proto.Transaction, height int64, timestamp int64) error {
+	var (
+		batches = []*txBatch{}
+		spent   = make(map[string]bool)
+	)
+	for i := 0; i < len(txx); i += batchVerifySize {
+		end := min(i+batchVerifySize, len(txx))
+		batch := &txBatch{
+			txx:      txx[i:end],
+			verifier: crypto.NewBatchVerifier(),
+		}
+		for _, tx := range batch.txx {
+			if err := c.validateTransaction(tx, height, timestamp, batch.verifier, spent); err != nil {
+				return c.validateTransactions(txx, height, timestamp)
+			}
+		}
+		batches = append(batches, batch)
+	}
+
+	verifyBatches(batches)
+
+	for _, batch := range batches {
+		if batch.failed {
+			return c.validateTransactions(txx, height, timestamp)
+		}
+	}
+	return nil
+}
+
+func verifyBatches(batches []*txBatch) {
+	var (
+		wg      sync.WaitGroup
+		jobs    = make(chan *txBatch)
+		workers = min(runtime.NumCPU(), len(batches))
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range jobs {
+				if !batch.verifier.Verify() {
+					batch.failed = true
+				}
+			}
+		}()
+	}
+	for _, batch := range batches {
+		jobs <- batch
+	}
+	close(jobs)
+	wg.Wait()
+}
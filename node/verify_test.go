@@ -0,0 +1,177 @@
+package node
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/64bitAryan/blocker/crypto"
+	"github.com/64bitAryan/blocker/proto"
+	"github.com/64bitAryan/blocker/script"
+	"github.com/64bitAryan/blocker/types"
+	"github.com/stretchr/testify/require"
+)
+
+// splitGenesis adds a block spending the genesis output into n outputs
+// owned by the god key and returns the splitting transaction.
+func splitGenesis(t *testing.T, chain *Chain, n int) *proto.Transaction {
+	privKey := crypto.NewPrivateKeyFromSeedStr(godSeed)
+	prevTx, err := chain.txStore.Get("0ff6af1c9aa971ef969f2cf72b2cfb8bf21d52c80c131d959de4d8edc6687e21")
+	require.Nil(t, err)
+
+	tx := &proto.Transaction{
+		Version: 1,
+		Inputs: []*proto.TxInput{
+			{
+				PrevTxHash:   types.HashTransaction(prevTx),
+				PrevOutIndex: 0,
+				PublicKey:    privKey.Public().Bytes(),
+			},
+		},
+	}
+	for i := 0; i < n; i++ {
+		tx.Outputs = append(tx.Outputs, &proto.TxOutput{
+			Amount:  1000 / int64(n),
+			Address: privKey.Public().Address().Bytes(),
+		})
+	}
+	tx.Inputs[0].Signature = types.SignTransaction(privKey, tx).Bytes()
+
+	block := randomBlock(t, chain)
+	block.Transactions = append(block.Transactions, tx)
+	types.SignBlock(privKey, block)
+	require.Nil(t, chain.AddBlock(block))
+	return tx
+}
+
+func spendOutputs(t *testing.T, prevTx *proto.Transaction) []*proto.Transaction {
+	var (
+		privKey   = crypto.NewPrivateKeyFromSeedStr(godSeed)
+		recipient = crypto.GeneratePrivateKey().Public().Address().Bytes()
+		txx       = []*proto.Transaction{}
+	)
+	for i, output := range prevTx.Outputs {
+		tx := &proto.Transaction{
+			Version: 1,
+			Inputs: []*proto.TxInput{
+				{
+					PrevTxHash:   types.HashTransaction(prevTx),
+					PrevOutIndex: uint32(i),
+					PublicKey:    privKey.Public().Bytes(),
+				},
+			},
+			Outputs: []*proto.TxOutput{
+				{
+					Amount:  output.Amount,
+					Address: recipient,
+				},
+			},
+		}
+		tx.Inputs[0].Signature = types.SignTransaction(privKey, tx).Bytes()
+		txx = append(txx, tx)
+	}
+	return txx
+}
+
+func TestValidateBlockBatch(t *testing.T) {
+	var (
+		chain   = NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+		privKey = crypto.NewPrivateKeyFromSeedStr(godSeed)
+		splitTx = splitGenesis(t, chain, 2*batchVerifySize+10)
+		block   = randomBlock(t, chain)
+	)
+	block.Transactions = spendOutputs(t, splitTx)
+	types.SignBlock(privKey, block)
+	require.Nil(t, chain.AddBlock(block))
+}
+
+func TestValidateBlockBatchInvalidSignature(t *testing.T) {
+	var (
+		chain   = NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+		privKey = crypto.NewPrivateKeyFromSeedStr(godSeed)
+		splitTx = splitGenesis(t, chain, batchVerifySize+10)
+		block   = randomBlock(t, chain)
+		txx     = spendOutputs(t, splitTx)
+	)
+	// signed by the wrong key
+	culprit := txx[batchVerifySize+3]
+	culprit.Inputs[0].Signature = types.SignTransaction(crypto.GeneratePrivateKey(), culprit).Bytes()
+
+	block.Transactions = txx
+	types.SignBlock(privKey, block)
+	err := chain.ValidateBlock(block)
+	require.NotNil(t, err)
+	require.ErrorContains(t, err, hex.EncodeToString(types.HashTransaction(culprit)))
+}
+
+func TestValidateBlockDoubleSpend(t *testing.T) {
+	for _, n := range []int{2, batchVerifySize + 10} {
+		var (
+			chain   = NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+			privKey = crypto.NewPrivateKeyFromSeedStr(godSeed)
+			splitTx = splitGenesis(t, chain, n)
+			block   = randomBlock(t, chain)
+			txx     = spendOutputs(t, splitTx)
+		)
+		// spends the first output again, in another batch
+		txx = append(txx, spendOutputs(t, splitTx)[0])
+
+		block.Transactions = txx
+		types.SignBlock(privKey, block)
+		require.ErrorContains(t, chain.AddBlock(block), "already spent in the block")
+
+		balance, err := chain.GetBalance(privKey.Public().Address())
+		require.Nil(t, err)
+		require.Equal(t, int64(n)*(1000/int64(n)), balance)
+	}
+}
+
+func TestValidateBlockBatchMultiSig(t *testing.T) {
+	var (
+		chain   = NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+		privKey = crypto.NewPrivateKeyFromSeedStr(godSeed)
+		signers = []*crypto.PrivateKeys{crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey()}
+		lock    = script.MultiSig(1, [][]byte{signers[0].Public().Bytes(), signers[1].Public().Bytes()})
+		splitTx = splitGenesis(t, chain, batchVerifySize+10)
+	)
+	// lock the outputs to either signer
+	lockTx := &proto.Transaction{Version: 1}
+	for i, output := range splitTx.Outputs {
+		lockTx.Inputs = append(lockTx.Inputs, &proto.TxInput{
+			PrevTxHash:   types.HashTransaction(splitTx),
+			PrevOutIndex: uint32(i),
+			PublicKey:    privKey.Public().Bytes(),
+		})
+		lockTx.Outputs = append(lockTx.Outputs, &proto.TxOutput{Amount: output.Amount, LockScript: lock})
+	}
+	sig := types.SignTransaction(privKey, lockTx).Bytes()
+	for _, input := range lockTx.Inputs {
+		input.Signature = sig
+	}
+	block := randomBlock(t, chain)
+	block.Transactions = []*proto.Transaction{lockTx}
+	types.SignBlock(privKey, block)
+	require.Nil(t, chain.AddBlock(block))
+
+	spend := func(signer *crypto.PrivateKeys) []*proto.Transaction {
+		txx := spendOutputs(t, lockTx)
+		for _, tx := range txx {
+			tx.Inputs[0].PublicKey = nil
+			tx.Inputs[0].Signature = nil
+			tx.Inputs[0].UnlockScript = script.UnlockMultiSig([][]byte{types.SignTransaction(signer, tx).Bytes()})
+		}
+		return txx
+	}
+
+	// signed by the second key, which a deferred check would pair with the
+	// first one
+	block = randomBlock(t, chain)
+	block.Transactions = spend(signers[1])
+	types.SignBlock(privKey, block)
+	require.Nil(t, chain.ValidateBlock(block))
+
+	block.Transactions = spend(signers[1])
+	culprit := spend(crypto.GeneratePrivateKey())[3]
+	block.Transactions[3] = culprit
+	types.SignBlock(privKey, block)
+	require.ErrorContains(t, chain.ValidateBlock(block), hex.EncodeToString(types.HashTransaction(culprit)))
+}
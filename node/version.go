@@ -0,0 +1,49 @@
+package node
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/64bitAryan/blocker/proto"
+)
+
+const (
+	DefaultNetworkID = "blocker-devnet"
+	defaultVersion   = "blocker-0.1"
+
+	// ProtocolVersion is bumped on every incompatible change of the wire
+	// protocol, peers below MinProtocolVersion are rejected.
+	ProtocolVersion    = 1
+	MinProtocolVersion = 1
+)
+
+// Features is the bit set of optional protocol features a node supports.
+type Features uint64
+
+const (
+	// FeatureInventory relays transactions and blocks with Announce and GetData
+	FeatureInventory Features = 1 << iota
+	// FeatureAddrExchange answers GetAddr
+	FeatureAddrExchange
+)
+
+const supportedFeatures = FeatureInventory | FeatureAddrExchange
+
+func (f Features) Has(feature Features) bool {
+	return f&feature == feature
+}
+
+// checkVersion rejects peers on another network or chain, or that speak an
+// incompatible protocol version.
+func (n *Node) checkVersion(v *proto.Version) error {
+	if v.NetworkID != n.NetworkID {
+		return fmt.Errorf("peer %s is on network %q, we are on %q", v.ListenAddr, v.NetworkID, n.NetworkID)
+	}
+	if v.ProtocolVersion < MinProtocolVersion {
+		return fmt.Errorf("peer %s speaks protocol version %d, minimum is %d", v.ListenAddr, v.ProtocolVersion, MinProtocolVersion)
+	}
+	if !bytes.Equal(v.GenesisHash, n.chain.GenesisHash()) {
+		return fmt.Errorf("peer %s has a different genesis block", v.ListenAddr)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package node
+
+import (
+	"context"
+	"testing"
+
+	"github.com/64bitAryan/blocker/proto"
+	"github.com/64bitAryan/blocker/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandshakeVersion(t *testing.T) {
+	n := NewNode(ServerConfig{ListenAddr: ":3000", Version: "blocker-1"})
+	v := n.getVersion()
+	assert.Equal(t, "blocker-1", v.Version)
+	assert.Equal(t, DefaultNetworkID, v.NetworkID)
+	assert.Equal(t, uint32(ProtocolVersion), v.ProtocolVersion)
+	assert.True(t, Features(v.Features).Has(FeatureInventory|FeatureAddrExchange))
+
+	remote := NewNode(ServerConfig{ListenAddr: ":4000"})
+	dialNodes(n, remote)
+	resp, err := n.Handshake(context.Background(), signedVersionFor(t, remote, n))
+	require.Nil(t, err)
+	assert.Equal(t, n.chain.GenesisHash(), resp.GenesisHash)
+	assert.ElementsMatch(t, []string{":4000"}, n.getPeerList())
+}
+
+func TestHandshakeRejectsIncompatiblePeers(t *testing.T) {
+	n := NewNode(ServerConfig{ListenAddr: ":3000", NetworkID: "testnet"})
+
+	tests := map[string]func(v *proto.Version){
+		"network": func(v *proto.Version) {
+			v.NetworkID = "devnet"
+		},
+		"protocol": func(v *proto.Version) {
+			v.ProtocolVersion = MinProtocolVersion - 1
+		},
+		"genesis": func(v *proto.Version) {
+			// a chain with other parameters
+			params := DefaultChainParams()
+			params.MaxBlockTxs++
+			genesis, err := DevGenesis(params).Block()
+			require.Nil(t, err)
+			v.GenesisHash = types.HashBlock(genesis)
+		},
+	}
+	for name, change := range tests {
+		t.Run(name, func(t *testing.T) {
+			v := n.getVersion()
+			v.ListenAddr = ":4000"
+			change(v)
+			_, err := n.Handshake(context.Background(), v)
+			assert.NotNil(t, err)
+			assert.Empty(t, n.getPeerList())
+		})
+	}
+}
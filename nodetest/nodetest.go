@@ -0,0 +1,339 @@
+// Package nodetest runs networks of nodes inside a test process. Nodes
+// listen on ephemeral ports of the loopback interface, so many networks
+// can run in parallel.
+package nodetest
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/64bitAryan/blocker/crypto"
+	"github.com/64bitAryan/blocker/node"
+	"github.com/64bitAryan/blocker/proto"
+	"github.com/64bitAryan/blocker/types"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	DefaultBlockTime = time.Millisecond * 100
+	DefaultTimeout   = time.Second * 10
+	pollInterval     = time.Millisecond * 10
+)
+
+// Option changes the config of node i before it is created.
+type Option func(i int, cfg *node.ServerConfig)
+
+// WithValidators makes the nodes with the given indexes the validator set
+// of the network, taking turns in that order.
+func WithValidators(indexes ...int) Option {
+	var (
+		keys       = make(map[int]*crypto.PrivateKeys)
+		validators = []*crypto.PublicKeys{}
+	)
+	for _, index := range indexes {
+		keys[index] = crypto.GeneratePrivateKey()
+		validators = append(validators, keys[index].Public())
+	}
+	return func(i int, cfg *node.ServerConfig) {
+		cfg.PrivateKey = keys[i]
+		cfg.Validators = validators
+	}
+}
+
+func WithBlockTime(d time.Duration) Option {
+	return func(i int, cfg *node.ServerConfig) {
+		cfg.Params.BlockTime = d
+	}
+}
+
+func WithEngine(engine node.Engine) Option {
+	return func(i int, cfg *node.ServerConfig) {
+		cfg.Engine = engine
+	}
+}
+
+// WithMiners gives the nodes with the given indexes a key to produce
+// blocks with, for engines without a validator set.
+func WithMiners(indexes ...int) Option {
+	keys := make(map[int]*crypto.PrivateKeys)
+	for _, index := range indexes {
+		keys[index] = crypto.GeneratePrivateKey()
+	}
+	return func(i int, cfg *node.ServerConfig) {
+		if key, ok := keys[i]; ok {
+			cfg.PrivateKey = key
+		}
+	}
+}
+
+func WithProduction(policy node.ProductionPolicy) Option {
+	return func(i int, cfg *node.ServerConfig) {
+		cfg.Production = policy
+	}
+}
+
+func WithTLS() Option {
+	return func(i int, cfg *node.ServerConfig) {
+		cfg.TLS = true
+	}
+}
+
+// Topology returns the links of a network of n nodes, the first node of
+// every link dials the second.
+type Topology func(n int) [][2]int
+
+// Line links every node to the next one.
+func Line(n int) [][2]int {
+	links := [][2]int{}
+	for i := 0; i+1 < n; i++ {
+		links = append(links, [2]int{i + 1, i})
+	}
+	return links
+}
+
+// Ring is a line with the last node linked to the first.
+func Ring(n int) [][2]int {
+	links := Line(n)
+	if n > 2 {
+		links = append(links, [2]int{0, n - 1})
+	}
+	return links
+}
+
+// Star links every node to the first one.
+func Star(n int) [][2]int {
+	links := [][2]int{}
+	for i := 1; i < n; i++ {
+		links = append(links, [2]int{i, 0})
+	}
+	return links
+}
+
+// FullMesh links every node to every other node.
+func FullMesh(n int) [][2]int {
+	links := [][2]int{}
+	for i := 0; i < n; i++ {
+		for j := 0; j < i; j++ {
+			links = append(links, [2]int{i, j})
+		}
+	}
+	return links
+}
+
+type Network struct {
+	t      testing.TB
+	Nodes  []*node.Node
+	cancel context.CancelFunc
+	errc   chan error
+}
+
+// New starts n nodes without any links between them, they are stopped when
+// the test finishes. Blocks are produced every DefaultBlockTime unless an
+// option says otherwise.
+func New(t testing.TB, n int, opts ...Option) *Network {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	network := &Network{
+		t:      t,
+		Nodes:  make([]*node.Node, n),
+		cancel: cancel,
+		errc:   make(chan error, n),
+	}
+	t.Cleanup(network.Stop)
+
+	for i := 0; i < n; i++ {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.Nil(t, err)
+		cfg := node.ServerConfig{
+			Version:    "blocker-test",
+			ListenAddr: ln.Addr().String(),
+			Params:     node.ChainParams{BlockTime: DefaultBlockTime},
+			// every node of the network shares the loopback
+			TrustLoopback: true,
+		}
+		for _, opt := range opts {
+			opt(i, &cfg)
+		}
+
+		nd := node.NewNode(cfg)
+		network.Nodes[i] = nd
+		go func() {
+			network.errc <- nd.Serve(ctx, ln, []string{})
+		}()
+	}
+	return network
+}
+
+// NewTopology starts n nodes and links them.
+func NewTopology(t testing.TB, n int, topology Topology, opts ...Option) *Network {
+	t.Helper()
+	network := New(t, n, opts...)
+	network.Connect(topology)
+	return network
+}
+
+func (network *Network) Stop() {
+	network.cancel()
+	for _, nd := range network.Nodes {
+		if nd != nil {
+			nd.Stop()
+		}
+	}
+}
+
+func (network *Network) Addr(i int) string {
+	return network.Nodes[i].ListenAddr
+}
+
+// Connect links the nodes as the topology says and waits until every link
+// is up.
+func (network *Network) Connect(topology Topology) {
+	network.t.Helper()
+	for _, link := range topology(len(network.Nodes)) {
+		network.Link(link[0], link[1])
+	}
+}
+
+// Link makes node a dial node b and waits until both see each other.
+func (network *Network) Link(a int, b int) {
+	network.t.Helper()
+	require.Nil(network.t, network.Nodes[a].Connect(network.Addr(b)))
+	network.WaitFor(fmt.Sprintf("link %d-%d", a, b), func() bool {
+		return network.linked(a, b) && network.linked(b, a)
+	})
+}
+
+func (network *Network) linked(a int, b int) bool {
+	for _, addr := range network.Nodes[a].Peers() {
+		if addr == network.Addr(b) {
+			return true
+		}
+	}
+	return false
+}
+
+// Partition cuts the link between node a and b, neither of them will
+// connect to the other until Heal is called.
+func (network *Network) Partition(a int, b int) {
+	network.t.Helper()
+	ctx := context.Background()
+	_, err := network.Nodes[a].BanPeer(ctx, &proto.BanRequest{Addr: network.Addr(b), Reason: "partitioned"})
+	require.Nil(network.t, err)
+	_, err = network.Nodes[b].BanPeer(ctx, &proto.BanRequest{Addr: network.Addr(a), Reason: "partitioned"})
+	require.Nil(network.t, err)
+}
+
+// Heal undoes Partition and links the nodes again.
+func (network *Network) Heal(a int, b int) {
+	network.t.Helper()
+	ctx := context.Background()
+	network.Nodes[a].UnbanPeer(ctx, &proto.BanRequest{Addr: network.Addr(b)})
+	network.Nodes[b].UnbanPeer(ctx, &proto.BanRequest{Addr: network.Addr(a)})
+	if !network.linked(a, b) {
+		network.Link(a, b)
+	}
+}
+
+// WaitFor polls cond until it is true and fails the test after
+// DefaultTimeout.
+func (network *Network) WaitFor(what string, cond func() bool) {
+	network.t.Helper()
+	deadline := time.Now().Add(DefaultTimeout)
+	for !cond() {
+		select {
+		case err := <-network.errc:
+			network.t.Fatalf("node stopped while waiting for %s: %v", what, err)
+		default:
+		}
+		if time.Now().After(deadline) {
+			network.t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// WaitForHeight waits until all nodes reached height.
+func (network *Network) WaitForHeight(height int) {
+	network.t.Helper()
+	network.WaitFor(fmt.Sprintf("height %d", height), func() bool {
+		for _, nd := range network.Nodes {
+			if nd.Height() < height {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// WaitForFinalized waits until all nodes finalized height.
+func (network *Network) WaitForFinalized(height int) {
+	network.t.Helper()
+	network.WaitFor(fmt.Sprintf("finalized height %d", height), func() bool {
+		for _, nd := range network.Nodes {
+			if nd.Chain().FinalizedHeight() < height {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// WaitForTx waits until all nodes have tx in their mempool or chain.
+func (network *Network) WaitForTx(tx *proto.Transaction) {
+	network.t.Helper()
+	hash := types.HashTransaction(tx)
+	network.WaitFor(fmt.Sprintf("tx %x", hash), func() bool {
+		for _, nd := range network.Nodes {
+			if !nd.HasTransaction(hash) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// SendTransaction hands tx to node i, like a client would.
+func (network *Network) SendTransaction(i int, tx *proto.Transaction) error {
+	_, err := network.Nodes[i].HandleTransaction(context.Background(), tx)
+	return err
+}
+
+// GenesisTx returns the transaction of the genesis block.
+func (network *Network) GenesisTx() *proto.Transaction {
+	network.t.Helper()
+	genesis, err := network.Nodes[0].Chain().GetBlockByHeight(0)
+	require.Nil(network.t, err)
+	return genesis.Transactions[0]
+}
+
+// Transfer returns a transaction signed by privKey that sends amount of the
+// output index of prevTx to the address, the rest goes back to privKey.
+func Transfer(privKey *crypto.PrivateKeys, prevTx *proto.Transaction, index int, to crypto.Address, amount int64) *proto.Transaction {
+	tx := &proto.Transaction{
+		Version: 1,
+		Inputs: []*proto.TxInput{
+			{
+				PrevTxHash:   types.HashTransaction(prevTx),
+				PrevOutIndex: uint32(index),
+				PublicKey:    privKey.Public().Bytes(),
+			},
+		},
+		Outputs: []*proto.TxOutput{
+			{
+				Amount:  amount,
+				Address: to.Bytes(),
+			},
+		},
+	}
+	if rest := prevTx.Outputs[index].Amount - amount; rest > 0 {
+		tx.Outputs = append(tx.Outputs, &proto.TxOutput{
+			Amount:  rest,
+			Address: privKey.Public().Address().Bytes(),
+		})
+	}
+	tx.Inputs[0].Signature = types.SignTransaction(privKey, tx).Bytes()
+	return tx
+}
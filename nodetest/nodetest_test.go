@@ -0,0 +1,150 @@
+package nodetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/64bitAryan/blocker/crypto"
+	"github.com/64bitAryan/blocker/node"
+	"github.com/64bitAryan/blocker/proto"
+	"github.com/64bitAryan/blocker/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopologies(t *testing.T) {
+	assert.Equal(t, [][2]int{{1, 0}, {2, 1}}, Line(3))
+	assert.Equal(t, [][2]int{{1, 0}, {2, 1}, {3, 2}, {0, 3}}, Ring(4))
+	assert.Equal(t, [][2]int{{1, 0}, {2, 0}, {3, 0}}, Star(4))
+	assert.Len(t, FullMesh(4), 6)
+}
+
+func TestNetworkReachesHeight(t *testing.T) {
+	network := NewTopology(t, 4, Ring, WithValidators(0))
+	for i := range network.Nodes {
+		assert.Len(t, network.Nodes[i].Peers(), 2)
+	}
+	network.WaitForHeight(3)
+}
+
+func TestTransactionPropagation(t *testing.T) {
+	network := NewTopology(t, 3, Line, WithTLS())
+
+	tx := Transfer(node.GenesisKey(), network.GenesisTx(), 0, crypto.GeneratePrivateKey().Public().Address(), 100)
+	require.Nil(t, network.SendTransaction(2, tx))
+	network.WaitForTx(tx)
+}
+
+func TestPartition(t *testing.T) {
+	var (
+		network = NewTopology(t, 2, Line)
+		key     = node.GenesisKey()
+		genesis = network.GenesisTx()
+	)
+	network.Partition(0, 1)
+	assert.Empty(t, network.Nodes[0].Peers())
+	assert.Empty(t, network.Nodes[1].Peers())
+
+	tx := Transfer(key, genesis, 0, crypto.GeneratePrivateKey().Public().Address(), 100)
+	require.Nil(t, network.SendTransaction(1, tx))
+	assert.Never(t, func() bool {
+		return network.Nodes[0].HasTransaction(types.HashTransaction(tx))
+	}, time.Millisecond*200, pollInterval)
+	assert.NotNil(t, network.Nodes[0].Connect(network.Addr(1)))
+
+	network.Heal(0, 1)
+	tx = Transfer(key, genesis, 0, crypto.GeneratePrivateKey().Public().Address(), 200)
+	require.Nil(t, network.SendTransaction(1, tx))
+	network.WaitForTx(tx)
+}
+
+func TestValidatorsTakeTurns(t *testing.T) {
+	network := NewTopology(t, 4, FullMesh, WithValidators(0, 1, 2), WithBlockTime(time.Millisecond*200))
+	network.WaitForHeight(6)
+
+	var (
+		chain   = network.Nodes[3].Chain()
+		vs      = chain.ValidatorSet()
+		signers = make(map[string]bool)
+	)
+	for height := 1; height <= 6; height++ {
+		b, err := chain.GetBlockByHeight(height)
+		require.Nil(t, err)
+		slot := vs.Slot(b.Header.Timestamp)
+		assert.Equal(t, vs.Proposer(slot).Bytes(), b.PublicKey)
+		signers[string(b.PublicKey)] = true
+	}
+	assert.Len(t, signers, 3)
+}
+
+func TestBlocksBecomeFinal(t *testing.T) {
+	network := NewTopology(t, 4, Ring, WithValidators(0, 1, 2))
+	network.WaitForFinalized(3)
+
+	var (
+		chain  = network.Nodes[3].Chain()
+		height = chain.FinalizedHeight()
+	)
+	commit, err := chain.GetCommit(height)
+	require.Nil(t, err)
+	b, err := chain.GetBlockByHeight(height)
+	require.Nil(t, err)
+	assert.Equal(t, types.HashBlock(b), commit.BlockHash)
+	assert.GreaterOrEqual(t, len(commit.Precommits), 3)
+}
+
+func TestInstamine(t *testing.T) {
+	// no block would come within the test but for the transaction
+	network := NewTopology(t, 2, Line, WithMiners(0), WithBlockTime(time.Hour), WithProduction(node.ProductionPolicy{Mode: node.ProduceInstamine}))
+	tx := Transfer(node.GenesisKey(), network.GenesisTx(), 0, crypto.GeneratePrivateKey().Public().Address(), 100)
+	require.Nil(t, network.SendTransaction(1, tx))
+	network.WaitForHeight(1)
+	b, err := network.Nodes[1].Chain().GetBlockByHeight(1)
+	require.Nil(t, err)
+	assert.Len(t, b.Transactions, 1)
+}
+
+func TestProofOfWork(t *testing.T) {
+	network := NewTopology(t, 3, Line, WithEngine(node.NewProofOfWorkEngine(12, time.Millisecond*50)), WithMiners(0))
+	network.WaitForHeight(8)
+	network.WaitForFinalized(2)
+
+	b, err := network.Nodes[0].Chain().GetBlockByHeight(2)
+	require.Nil(t, err)
+	final, err := network.Nodes[2].Chain().GetBlockByHeight(2)
+	require.Nil(t, err)
+	assert.Equal(t, types.HashBlock(b), types.HashBlock(final))
+}
+
+func TestGovernanceAddsValidator(t *testing.T) {
+	candidate := crypto.GeneratePrivateKey()
+	network := NewTopology(t, 3, FullMesh, WithValidators(0, 1), func(i int, cfg *node.ServerConfig) {
+		if i == 2 {
+			cfg.PrivateKey = candidate
+		}
+	})
+	var (
+		newKey = candidate.Public()
+		change = &proto.ValidatorChange{
+			Action:    proto.ValidatorChange_ADD,
+			PublicKey: newKey.Bytes(),
+		}
+		proposal = types.NewValidatorProposal(network.Nodes[0].PrivateKey, change)
+	)
+	require.Nil(t, network.SendTransaction(0, proposal))
+	network.WaitFor("proposal on chain", func() bool {
+		_, err := network.Nodes[1].Chain().GetTransactionByHash(types.HashTransaction(proposal))
+		return err == nil
+	})
+	approval := types.NewValidatorApproval(network.Nodes[1].PrivateKey, types.ProposalID(change))
+	require.Nil(t, network.SendTransaction(1, approval))
+
+	network.WaitFor("new validator block", func() bool {
+		chain := network.Nodes[0].Chain()
+		b, err := chain.GetBlockByHeight(chain.Height())
+		return err == nil && string(b.PublicKey) == string(newKey.Bytes())
+	})
+	for _, nd := range network.Nodes {
+		assert.True(t, nd.Chain().ValidatorSet().Contains(newKey))
+	}
+}
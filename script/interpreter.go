@@ -0,0 +1,339 @@
+package script
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/64bitAryan/blocker/crypto"
+)
+
+// Context carries the transaction data a script can be checked against.
+type Context struct {
+	// SigHash is the message signatures are verified against
+	SigHash []byte
+	// Height of the block the spending transaction is included in
+	Height int64
+	// Time (unix nano) of the block the spending transaction is included in
+	Time int64
+	// CheckSig replaces the ed25519 signature check when set, it is used to
+	// defer signature checks into a crypto.BatchVerifier. Multisig scripts
+	// with fewer signatures than keys do not use it.
+	CheckSig func(sig []byte, pubKey []byte, msg []byte) bool
+}
+
+type stack struct {
+	items [][]byte
+}
+
+func (s *stack) push(b []byte) error {
+	if len(b) > MaxElementSize {
+		return fmt.Errorf("element size (%d) exceeds limit (%d)", len(b), MaxElementSize)
+	}
+	if len(s.items) >= MaxStackSize {
+		return fmt.Errorf("stack size exceeds limit (%d)", MaxStackSize)
+	}
+	s.items = append(s.items, b)
+	return nil
+}
+
+func (s *stack) pop() ([]byte, error) {
+	if len(s.items) == 0 {
+		return nil, fmt.Errorf("stack underflow")
+	}
+	b := s.items[len(s.items)-1]
+	s.items = s.items[:len(s.items)-1]
+	return b, nil
+}
+
+func (s *stack) popInt() (int64, error) {
+	b, err := s.pop()
+	if err != nil {
+		return 0, err
+	}
+	return decodeNum(b)
+}
+
+func (s *stack) peek() ([]byte, error) {
+	if len(s.items) == 0 {
+		return nil, fmt.Errorf("stack underflow")
+	}
+	return s.items[len(s.items)-1], nil
+}
+
+func isTrue(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func boolBytes(v bool) []byte {
+	if v {
+		return []byte{1}
+	}
+	return []byte{}
+}
+
+type engine struct {
+	ctx   Context
+	stack *stack
+	cost  int
+}
+
+// Execute runs the unlock script followed by the lock script on a shared
+// stack. The spend is valid when both run without error and the top of
+// the stack is true.
+func Execute(unlock []byte, lock []byte, ctx Context) error {
+	if !IsPushOnly(unlock) {
+		return fmt.Errorf("unlock script is not push only")
+	}
+	e := &engine{
+		ctx:   ctx,
+		stack: &stack{},
+	}
+	if err := e.run(unlock); err != nil {
+		return fmt.Errorf("unlock script: %w", err)
+	}
+	if err := e.run(lock); err != nil {
+		return fmt.Errorf("lock script: %w", err)
+	}
+	top, err := e.stack.peek()
+	if err != nil {
+		return fmt.Errorf("empty stack after execution")
+	}
+	if !isTrue(top) {
+		return fmt.Errorf("script evaluated to false")
+	}
+	return nil
+}
+
+func (e *engine) charge(n int) error {
+	e.cost += n
+	if e.cost > MaxCost {
+		return fmt.Errorf("script cost exceeds limit (%d)", MaxCost)
+	}
+	return nil
+}
+
+func (e *engine) run(script []byte) error {
+	instructions, err := Parse(script)
+	if err != nil {
+		return err
+	}
+	for _, in := range instructions {
+		if err := e.charge(in.Op.cost()); err != nil {
+			return err
+		}
+		if err := e.step(in); err != nil {
+			return fmt.Errorf("%s: %w", in.Op, err)
+		}
+	}
+	return nil
+}
+
+func (e *engine) step(in Instruction) error {
+	s := e.stack
+	switch op := in.Op; {
+	case op == OP_0:
+		return s.push([]byte{})
+	case op < OP_PUSHDATA1 || op == OP_PUSHDATA1 || op == OP_PUSHDATA2:
+		return s.push(in.Data)
+	case op >= OP_1 && op <= OP_16:
+		return s.push(EncodeNum(int64(op - OP_1 + 1)))
+
+	case op == OP_VERIFY:
+		return e.verify()
+	case op == OP_RETURN:
+		return fmt.Errorf("script is unspendable")
+	case op == OP_DROP:
+		_, err := s.pop()
+		return err
+	case op == OP_DUP:
+		b, err := s.peek()
+		if err != nil {
+			return err
+		}
+		return s.push(b)
+	case op == OP_SWAP:
+		a, err := s.pop()
+		if err != nil {
+			return err
+		}
+		b, err := s.pop()
+		if err != nil {
+			return err
+		}
+		s.push(a)
+		return s.push(b)
+
+	case op == OP_EQUAL, op == OP_EQUALVERIFY:
+		a, err := s.pop()
+		if err != nil {
+			return err
+		}
+		b, err := s.pop()
+		if err != nil {
+			return err
+		}
+		s.push(boolBytes(bytes.Equal(a, b)))
+		if op == OP_EQUALVERIFY {
+			return e.verify()
+		}
+		return nil
+
+	case op == OP_SHA256:
+		b, err := s.pop()
+		if err != nil {
+			return err
+		}
+		hash := sha256.Sum256(b)
+		return s.push(hash[:])
+	case op == OP_ADDR:
+		b, err := s.pop()
+		if err != nil {
+			return err
+		}
+		if len(b) != crypto.PubKeyLen {
+			return fmt.Errorf("invalid public key length (%d)", len(b))
+		}
+		return s.push(crypto.PublicKeyFromBytes(b).Address().Bytes())
+
+	case op == OP_CHECKSIG, op == OP_CHECKSIGVERIFY:
+		pubKey, err := s.pop()
+		if err != nil {
+			return err
+		}
+		sig, err := s.pop()
+		if err != nil {
+			return err
+		}
+		s.push(boolBytes(e.checkSig(sig, pubKey)))
+		if op == OP_CHECKSIGVERIFY {
+			return e.verify()
+		}
+		return nil
+
+	case op == OP_CHECKMULTISIG, op == OP_CHECKMULTISIGVERIFY:
+		ok, err := e.checkMultiSig()
+		if err != nil {
+			return err
+		}
+		s.push(boolBytes(ok))
+		if op == OP_CHECKMULTISIGVERIFY {
+			return e.verify()
+		}
+		return nil
+
+	case op == OP_CHECKHEIGHTVERIFY:
+		// the height is left on the stack, like bitcoin's CHECKLOCKTIMEVERIFY
+		b, err := s.peek()
+		if err != nil {
+			return err
+		}
+		height, err := decodeNum(b)
+		if err != nil {
+			return err
+		}
+		if e.ctx.Height < height {
+			return fmt.Errorf("height (%d) is below required height (%d)", e.ctx.Height, height)
+		}
+		return nil
+	case op == OP_CHECKTIMEVERIFY:
+		b, err := s.peek()
+		if err != nil {
+			return err
+		}
+		ts, err := decodeNum(b)
+		if err != nil {
+			return err
+		}
+		if e.ctx.Time < ts {
+			return fmt.Errorf("time (%d) is before required time (%d)", e.ctx.Time, ts)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown opcode")
+}
+
+func (e *engine) verify() error {
+	b, err := e.stack.pop()
+	if err != nil {
+		return err
+	}
+	if !isTrue(b) {
+		return fmt.Errorf("verify failed")
+	}
+	return nil
+}
+
+// stack layout: <sig 1> ... <sig m> <m> <key 1> ... <key n> <n>
+func (e *engine) checkMultiSig() (bool, error) {
+	s := e.stack
+	n, err := s.popInt()
+	if err != nil {
+		return false, err
+	}
+	if n < 1 || n > MaxMultiSigKey {
+		return false, fmt.Errorf("invalid number of keys (%d)", n)
+	}
+	if err := e.charge(int(n) * OP_CHECKSIG.cost()); err != nil {
+		return false, err
+	}
+	keys := make([][]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		if keys[i], err = s.pop(); err != nil {
+			return false, err
+		}
+	}
+	m, err := s.popInt()
+	if err != nil {
+		return false, err
+	}
+	if m < 1 || m > n {
+		return false, fmt.Errorf("invalid number of signatures (%d) for %d keys", m, n)
+	}
+	sigs := make([][]byte, m)
+	for i := m - 1; i >= 0; i-- {
+		if sigs[i], err = s.pop(); err != nil {
+			return false, err
+		}
+	}
+
+	// signatures have to follow the order of the keys. Unless every key
+	// signs, matching them to the keys needs the outcome of each check, so
+	// the checks can not be deferred to CheckSig.
+	check := e.checkSig
+	if m < n {
+		check = func(sig []byte, pubKey []byte) bool {
+			return checkSig(sig, pubKey, e.ctx.SigHash)
+		}
+	}
+	k := 0
+	for _, sig := range sigs {
+		for k < len(keys) && !check(sig, keys[k]) {
+			k++
+		}
+		if k == len(keys) {
+			return false, nil
+		}
+		k++
+	}
+	return true, nil
+}
+
+func (e *engine) checkSig(sig []byte, pubKey []byte) bool {
+	if e.ctx.CheckSig != nil {
+		return e.ctx.CheckSig(sig, pubKey, e.ctx.SigHash)
+	}
+	return checkSig(sig, pubKey, e.ctx.SigHash)
+}
+
+func checkSig(sig []byte, pubKey []byte, msg []byte) bool {
+	if len(sig) != crypto.SignatureLen || len(pubKey) != crypto.PubKeyLen {
+		return false
+	}
+	return crypto.SignatureFromBytes(sig).Verify(crypto.PublicKeyFromBytes(pubKey), msg)
+}
@@ -0,0 +1,137 @@
+package script
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/64bitAryan/blocker/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecute(t *testing.T) {
+	var (
+		msg      = []byte("spend me")
+		keys     = []*crypto.PrivateKeys{crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey()}
+		pubKeys  = [][]byte{keys[0].Public().Bytes(), keys[1].Public().Bytes(), keys[2].Public().Bytes()}
+		sigs     = [][]byte{keys[0].Sign(msg).Bytes(), keys[1].Sign(msg).Bytes(), keys[2].Sign(msg).Bytes()}
+		address  = keys[0].Public().Address().Bytes()
+		preimage = []byte("secret")
+		digest   = sha256.Sum256(preimage)
+		ctx      = Context{SigHash: msg, Height: 100, Time: 5000}
+	)
+
+	tests := []struct {
+		name   string
+		unlock []byte
+		lock   []byte
+		valid  bool
+	}{
+		{"true", nil, NewBuilder().AddInt(1).Script(), true},
+		{"false", nil, NewBuilder().AddInt(0).Script(), false},
+		{"empty", nil, nil, false},
+		{"return", nil, NewBuilder().AddInt(1).AddOp(OP_RETURN).Script(), false},
+		{"unknown opcode", nil, []byte{byte(OP_1), 0xff}, false},
+		{"underflow", nil, []byte{byte(OP_DUP)}, false},
+		{"drop", NewBuilder().AddInt(1).AddInt(0).Script(), []byte{byte(OP_DROP)}, true},
+		{"swap", NewBuilder().AddInt(1).AddInt(0).Script(), []byte{byte(OP_SWAP)}, true},
+		{"verify", nil, NewBuilder().AddInt(0).AddOp(OP_VERIFY).AddInt(1).Script(), false},
+
+		{"equal", NewBuilder().AddData([]byte("a")).Script(), NewBuilder().AddData([]byte("a")).AddOp(OP_EQUAL).Script(), true},
+		{"not equal", NewBuilder().AddData([]byte("a")).Script(), NewBuilder().AddData([]byte("b")).AddOp(OP_EQUAL).Script(), false},
+		{"equalverify", NewBuilder().AddData([]byte("a")).Script(), NewBuilder().AddData([]byte("b")).AddOp(OP_EQUALVERIFY).AddInt(1).Script(), false},
+
+		{"hash lock", NewBuilder().AddData(preimage).Script(), NewBuilder().AddOp(OP_SHA256).AddData(digest[:]).AddOp(OP_EQUAL).Script(), true},
+		{"hash lock wrong preimage", NewBuilder().AddData([]byte("guess")).Script(), NewBuilder().AddOp(OP_SHA256).AddData(digest[:]).AddOp(OP_EQUAL).Script(), false},
+
+		{"pay to address", UnlockPayToAddress(sigs[0], pubKeys[0]), PayToAddress(address), true},
+		{"pay to address wrong key", UnlockPayToAddress(sigs[1], pubKeys[1]), PayToAddress(address), false},
+		{"pay to address wrong sig", UnlockPayToAddress(sigs[1], pubKeys[0]), PayToAddress(address), false},
+		{"pay to address short sig", UnlockPayToAddress(sigs[0][:10], pubKeys[0]), PayToAddress(address), false},
+		{"pay to address short key", UnlockPayToAddress(sigs[0], pubKeys[0][:10]), PayToAddress(address), false},
+
+		{"checksigverify", NewBuilder().AddData(sigs[0]).Script(), NewBuilder().AddData(pubKeys[0]).AddOp(OP_CHECKSIGVERIFY).AddInt(1).Script(), true},
+
+		{"multisig 2 of 3", UnlockMultiSig([][]byte{sigs[0], sigs[2]}), MultiSig(2, pubKeys), true},
+		{"multisig 3 of 3", UnlockMultiSig(sigs), MultiSig(3, pubKeys), true},
+		{"multisig wrong order", UnlockMultiSig([][]byte{sigs[2], sigs[0]}), MultiSig(2, pubKeys), false},
+		{"multisig duplicate sig", UnlockMultiSig([][]byte{sigs[0], sigs[0]}), MultiSig(2, pubKeys), false},
+		{"multisig missing sig", UnlockMultiSig([][]byte{sigs[0]}), MultiSig(2, pubKeys), false},
+		{"multisig m > n", UnlockMultiSig(sigs), MultiSig(4, pubKeys), false},
+
+		{"height reached", nil, NewBuilder().AddInt(100).AddOp(OP_CHECKHEIGHTVERIFY).Script(), true},
+		{"height not reached", nil, NewBuilder().AddInt(101).AddOp(OP_CHECKHEIGHTVERIFY).Script(), false},
+		{"time reached", nil, NewBuilder().AddInt(5000).AddOp(OP_CHECKTIMEVERIFY).Script(), true},
+		{"time not reached", nil, NewBuilder().AddInt(5001).AddOp(OP_CHECKTIMEVERIFY).Script(), false},
+		{
+			"time locked pay to address",
+			UnlockPayToAddress(sigs[0], pubKeys[0]),
+			append(NewBuilder().AddInt(4000).AddOp(OP_CHECKTIMEVERIFY).AddOp(OP_DROP).Script(), PayToAddress(address)...),
+			true,
+		},
+
+		{"unlock not push only", []byte{byte(OP_1), byte(OP_DUP)}, NewBuilder().AddInt(1).Script(), false},
+	}
+
+	for _, tt := range tests {
+		err := Execute(tt.unlock, tt.lock, ctx)
+		if tt.valid {
+			assert.Nil(t, err, tt.name)
+		} else {
+			assert.NotNil(t, err, tt.name)
+		}
+	}
+}
+
+func TestExecuteDeferredCheckSig(t *testing.T) {
+	var (
+		msg      = []byte("spend me")
+		keys     = []*crypto.PrivateKeys{crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey()}
+		pubKeys  = [][]byte{keys[0].Public().Bytes(), keys[1].Public().Bytes()}
+		sigs     = [][]byte{keys[0].Sign(msg).Bytes(), keys[1].Sign(msg).Bytes()}
+		deferred = 0
+		ctx      = Context{
+			SigHash: msg,
+			// accepts everything, like the batch does until it is verified
+			CheckSig: func(sig []byte, pubKey []byte, msg []byte) bool {
+				deferred++
+				return true
+			},
+		}
+	)
+
+	// every key signs, each signature is deferred with its key
+	assert.Nil(t, Execute(UnlockMultiSig(sigs), MultiSig(2, pubKeys), ctx))
+	assert.Equal(t, 2, deferred)
+
+	// the signature of the second key must not be matched to the first
+	deferred = 0
+	assert.Nil(t, Execute(UnlockMultiSig(sigs[1:]), MultiSig(1, pubKeys), ctx))
+	stranger := crypto.GeneratePrivateKey().Sign(msg).Bytes()
+	assert.NotNil(t, Execute(UnlockMultiSig([][]byte{stranger}), MultiSig(1, pubKeys), ctx))
+	assert.Zero(t, deferred)
+}
+
+func TestExecuteLimits(t *testing.T) {
+	ctx := Context{}
+
+	// too many elements on the stack
+	unlock := bytes.Repeat([]byte{byte(OP_1)}, MaxStackSize+1)
+	assert.NotNil(t, Execute(unlock, nil, ctx))
+
+	// element too large
+	unlock = NewBuilder().AddData(make([]byte, MaxElementSize+1)).Script()
+	assert.NotNil(t, Execute(unlock, []byte{byte(OP_DROP), byte(OP_1)}, ctx))
+
+	// cost of hashing too much
+	lock := append([]byte{byte(OP_1)}, bytes.Repeat([]byte{byte(OP_SHA256)}, MaxCost/OP_SHA256.cost()+1)...)
+	assert.NotNil(t, Execute(nil, lock, ctx))
+
+	// cost of checking too many signatures
+	lock = []byte{}
+	for i := 0; i < MaxCost/OP_CHECKSIG.cost()+1; i++ {
+		lock = append(lock, byte(OP_0), byte(OP_0), byte(OP_CHECKSIG), byte(OP_DROP))
+	}
+	lock = append(lock, byte(OP_1))
+	assert.NotNil(t, Execute(nil, lock, ctx))
+}
@@ -0,0 +1,250 @@
+package script
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+type Opcode byte
+
+// Opcodes 0x01 - 0x4b push the next n bytes on the stack.
+const (
+	OP_0         Opcode = 0x00
+	OP_PUSHDATA1 Opcode = 0x4c
+	OP_PUSHDATA2 Opcode = 0x4d
+	OP_1         Opcode = 0x51
+	OP_16        Opcode = 0x60
+
+	OP_VERIFY Opcode = 0x69
+	OP_RETURN Opcode = 0x6a
+	OP_DROP   Opcode = 0x75
+	OP_DUP    Opcode = 0x76
+	OP_SWAP   Opcode = 0x7c
+
+	OP_EQUAL       Opcode = 0x87
+	OP_EQUALVERIFY Opcode = 0x88
+
+	OP_SHA256 Opcode = 0xa8
+	OP_ADDR   Opcode = 0xa9
+
+	OP_CHECKSIG            Opcode = 0xac
+	OP_CHECKSIGVERIFY      Opcode = 0xad
+	OP_CHECKMULTISIG       Opcode = 0xae
+	OP_CHECKMULTISIGVERIFY Opcode = 0xaf
+
+	OP_CHECKHEIGHTVERIFY Opcode = 0xb1
+	OP_CHECKTIMEVERIFY   Opcode = 0xb2
+)
+
+const (
+	MaxScriptSize  = 10_000
+	MaxElementSize = 520
+	MaxStackSize   = 1000
+	MaxCost        = 2000
+	MaxMultiSigKey = 16
+)
+
+var opcodeNames = map[Opcode]string{
+	OP_0:                   "OP_0",
+	OP_PUSHDATA1:           "OP_PUSHDATA1",
+	OP_PUSHDATA2:           "OP_PUSHDATA2",
+	OP_VERIFY:              "OP_VERIFY",
+	OP_RETURN:              "OP_RETURN",
+	OP_DROP:                "OP_DROP",
+	OP_DUP:                 "OP_DUP",
+	OP_SWAP:                "OP_SWAP",
+	OP_EQUAL:               "OP_EQUAL",
+	OP_EQUALVERIFY:         "OP_EQUALVERIFY",
+	OP_SHA256:              "OP_SHA256",
+	OP_ADDR:                "OP_ADDR",
+	OP_CHECKSIG:            "OP_CHECKSIG",
+	OP_CHECKSIGVERIFY:      "OP_CHECKSIGVERIFY",
+	OP_CHECKMULTISIG:       "OP_CHECKMULTISIG",
+	OP_CHECKMULTISIGVERIFY: "OP_CHECKMULTISIGVERIFY",
+	OP_CHECKHEIGHTVERIFY:   "OP_CHECKHEIGHTVERIFY",
+	OP_CHECKTIMEVERIFY:     "OP_CHECKTIMEVERIFY",
+}
+
+func (op Opcode) String() string {
+	if name, ok := opcodeNames[op]; ok {
+		return name
+	}
+	if op >= OP_1 && op <= OP_16 {
+		return fmt.Sprintf("OP_%d", op-OP_1+1)
+	}
+	if op > OP_0 && op < OP_PUSHDATA1 {
+		return fmt.Sprintf("OP_DATA_%d", op)
+	}
+	return fmt.Sprintf("OP_UNKNOWN_%#x", byte(op))
+}
+
+// cost of executing a single opcode, counted against MaxCost
+func (op Opcode) cost() int {
+	switch op {
+	case OP_SHA256, OP_ADDR:
+		return 10
+	case OP_CHECKSIG, OP_CHECKSIGVERIFY:
+		return 50
+	case OP_CHECKMULTISIG, OP_CHECKMULTISIGVERIFY:
+		// the per key cost is charged while executing
+		return 10
+	}
+	return 1
+}
+
+// Instruction is a single parsed opcode with its push data, if any.
+type Instruction struct {
+	Op   Opcode
+	Data []byte
+}
+
+func Parse(script []byte) ([]Instruction, error) {
+	if len(script) > MaxScriptSize {
+		return nil, fmt.Errorf("script size (%d) exceeds limit (%d)", len(script), MaxScriptSize)
+	}
+	instructions := []Instruction{}
+	for i := 0; i < len(script); {
+		op := Opcode(script[i])
+		i++
+
+		var n int
+		switch {
+		case op > OP_0 && op < OP_PUSHDATA1:
+			n = int(op)
+		case op == OP_PUSHDATA1:
+			if i+1 > len(script) {
+				return nil, fmt.Errorf("%s: missing length", op)
+			}
+			n = int(script[i])
+			i++
+		case op == OP_PUSHDATA2:
+			if i+2 > len(script) {
+				return nil, fmt.Errorf("%s: missing length", op)
+			}
+			n = int(binary.LittleEndian.Uint16(script[i:]))
+			i += 2
+		default:
+			instructions = append(instructions, Instruction{Op: op})
+			continue
+		}
+
+		if i+n > len(script) {
+			return nil, fmt.Errorf("%s: push of %d bytes past end of script", op, n)
+		}
+		instructions = append(instructions, Instruction{Op: op, Data: script[i : i+n]})
+		i += n
+	}
+	return instructions, nil
+}
+
+// IsPushOnly reports whether the script only pushes data on the stack.
+// Unlock scripts are required to be push only.
+func IsPushOnly(script []byte) bool {
+	instructions, err := Parse(script)
+	if err != nil {
+		return false
+	}
+	for _, in := range instructions {
+		if in.Op > OP_16 {
+			return false
+		}
+	}
+	return true
+}
+
+type Builder struct {
+	script []byte
+}
+
+func NewBuilder() *Builder {
+	return &Builder{
+		script: []byte{},
+	}
+}
+
+func (b *Builder) AddOp(op Opcode) *Builder {
+	b.script = append(b.script, byte(op))
+	return b
+}
+
+func (b *Builder) AddData(data []byte) *Builder {
+	switch n := len(data); {
+	case n == 0:
+		b.script = append(b.script, byte(OP_0))
+	case n < int(OP_PUSHDATA1):
+		b.script = append(b.script, byte(n))
+	case n <= 0xff:
+		b.script = append(b.script, byte(OP_PUSHDATA1), byte(n))
+	default:
+		b.script = append(b.script, byte(OP_PUSHDATA2))
+		b.script = binary.LittleEndian.AppendUint16(b.script, uint16(n))
+	}
+	b.script = append(b.script, data...)
+	return b
+}
+
+func (b *Builder) AddInt(n int64) *Builder {
+	if n == 0 {
+		return b.AddOp(OP_0)
+	}
+	if n >= 1 && n <= 16 {
+		return b.AddOp(OP_1 + Opcode(n-1))
+	}
+	return b.AddData(EncodeNum(n))
+}
+
+func (b *Builder) Script() []byte {
+	return b.script
+}
+
+// EncodeNum encodes n as a minimal little endian integer.
+func EncodeNum(n int64) []byte {
+	buf := binary.LittleEndian.AppendUint64(nil, uint64(n))
+	for len(buf) > 1 && buf[len(buf)-1] == 0 {
+		buf = buf[:len(buf)-1]
+	}
+	return buf
+}
+
+func decodeNum(b []byte) (int64, error) {
+	if len(b) > 8 {
+		return 0, fmt.Errorf("number of %d bytes is too large", len(b))
+	}
+	buf := make([]byte, 8)
+	copy(buf, b)
+	return int64(binary.LittleEndian.Uint64(buf)), nil
+}
+
+// PayToAddress returns the lock script used for outputs that do not carry
+// one: OP_DUP OP_ADDR <address> OP_EQUALVERIFY OP_CHECKSIG
+func PayToAddress(address []byte) []byte {
+	return NewBuilder().
+		AddOp(OP_DUP).
+		AddOp(OP_ADDR).
+		AddData(address).
+		AddOp(OP_EQUALVERIFY).
+		AddOp(OP_CHECKSIG).
+		Script()
+}
+
+func UnlockPayToAddress(sig []byte, pubKey []byte) []byte {
+	return NewBuilder().AddData(sig).AddData(pubKey).Script()
+}
+
+// MultiSig returns a lock script requiring m valid signatures of the given keys.
+func MultiSig(m int, pubKeys [][]byte) []byte {
+	b := NewBuilder().AddInt(int64(m))
+	for _, key := range pubKeys {
+		b.AddData(key)
+	}
+	return b.AddInt(int64(len(pubKeys))).AddOp(OP_CHECKMULTISIG).Script()
+}
+
+// UnlockMultiSig expects the signatures in the same order as the keys of the lock script.
+func UnlockMultiSig(sigs [][]byte) []byte {
+	b := NewBuilder()
+	for _, sig := range sigs {
+		b.AddData(sig)
+	}
+	return b.Script()
+}
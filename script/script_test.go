@@ -0,0 +1,79 @@
+package script
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuilderAddData(t *testing.T) {
+	tests := []struct {
+		size   int
+		prefix []byte
+	}{
+		{0, []byte{byte(OP_0)}},
+		{1, []byte{0x01}},
+		{75, []byte{0x4b}},
+		{76, []byte{byte(OP_PUSHDATA1), 76}},
+		{255, []byte{byte(OP_PUSHDATA1), 0xff}},
+		{256, []byte{byte(OP_PUSHDATA2), 0x00, 0x01}},
+	}
+	for _, tt := range tests {
+		data := bytes.Repeat([]byte{0xaa}, tt.size)
+		s := NewBuilder().AddData(data).Script()
+		assert.Equal(t, tt.prefix, s[:len(tt.prefix)], "size %d", tt.size)
+
+		instructions, err := Parse(s)
+		require.Nil(t, err)
+		require.Len(t, instructions, 1)
+		assert.Equal(t, data, append([]byte{}, instructions[0].Data...))
+	}
+}
+
+func TestBuilderAddInt(t *testing.T) {
+	assert.Equal(t, []byte{byte(OP_0)}, NewBuilder().AddInt(0).Script())
+	assert.Equal(t, []byte{byte(OP_1)}, NewBuilder().AddInt(1).Script())
+	assert.Equal(t, []byte{byte(OP_16)}, NewBuilder().AddInt(16).Script())
+	assert.Equal(t, []byte{0x01, 17}, NewBuilder().AddInt(17).Script())
+	assert.Equal(t, []byte{0x02, 0x00, 0x01}, NewBuilder().AddInt(256).Script())
+}
+
+func TestEncodeNum(t *testing.T) {
+	for _, n := range []int64{0, 1, 255, 256, 1 << 40, 1<<62 + 7} {
+		v, err := decodeNum(EncodeNum(n))
+		require.Nil(t, err)
+		assert.Equal(t, n, v)
+	}
+	_, err := decodeNum(make([]byte, 9))
+	assert.NotNil(t, err)
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := [][]byte{
+		{0x05, 0x01, 0x02},
+		{byte(OP_PUSHDATA1)},
+		{byte(OP_PUSHDATA1), 0x02, 0x01},
+		{byte(OP_PUSHDATA2), 0x01},
+		make([]byte, MaxScriptSize+1),
+	}
+	for _, s := range tests {
+		_, err := Parse(s)
+		assert.NotNil(t, err, "%x", s)
+	}
+}
+
+func TestIsPushOnly(t *testing.T) {
+	assert.True(t, IsPushOnly(UnlockPayToAddress(make([]byte, 64), make([]byte, 32))))
+	assert.True(t, IsPushOnly(NewBuilder().AddInt(3).AddData([]byte("foo")).Script()))
+	assert.False(t, IsPushOnly(PayToAddress(make([]byte, 20))))
+	assert.False(t, IsPushOnly([]byte{0x02, 0x01}))
+}
+
+func TestOpcodeString(t *testing.T) {
+	assert.Equal(t, "OP_CHECKSIG", OP_CHECKSIG.String())
+	assert.Equal(t, "OP_5", (OP_1 + 4).String())
+	assert.Equal(t, "OP_DATA_20", Opcode(20).String())
+	assert.Equal(t, "OP_UNKNOWN_0xff", Opcode(0xff).String())
+}
@@ -30,11 +30,7 @@ func (h TxHash) Equals(other merkletree.Content) (bool, error) {
 }
 
 func SignBlock(pk *crypto.PrivateKeys, b *proto.Block) *crypto.Signature {
-	hash := HashBlock(b)
-	sig := pk.Sign(hash)
-	b.PublicKey = pk.Public().Bytes()
-	b.Signature = sig.Bytes()
-
+	// the root hash is part of the header, so it has to be set before signing
 	if len(b.Transactions) > 0 {
 
 		tree, err := GetMerkleTree(b)
@@ -44,6 +40,12 @@ func SignBlock(pk *crypto.PrivateKeys, b *proto.Block) *crypto.Signature {
 
 		b.Header.RootHash = tree.MerkleRoot()
 	}
+	b.Header.EvidenceHash = HashEvidence(b.Evidence)
+
+	hash := HashBlock(b)
+	sig := pk.Sign(hash)
+	b.PublicKey = pk.Public().Bytes()
+	b.Signature = sig.Bytes()
 
 	return sig
 }
@@ -55,6 +57,10 @@ func VerifyBlock(b *proto.Block) bool {
 		}
 	}
 
+	if !bytes.Equal(b.Header.EvidenceHash, HashEvidence(b.Evidence)) {
+		return false
+	}
+
 	if len(b.PublicKey) != crypto.PubKeyLen {
 		return false
 	}
@@ -0,0 +1,83 @@
+package types
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/64bitAryan/blocker/crypto"
+	"github.com/64bitAryan/blocker/proto"
+	pb "google.golang.org/protobuf/proto"
+)
+
+func SignedHeaderOf(b *proto.Block) *proto.SignedHeader {
+	return &proto.SignedHeader{
+		Header:    b.Header,
+		PublicKey: b.PublicKey,
+		Signature: b.Signature,
+	}
+}
+
+// NewEvidence returns the evidence of the signer of a and b, the headers
+// are ordered by hash so that both orders give the same evidence.
+func NewEvidence(a, b *proto.Block) *proto.Evidence {
+	if bytes.Compare(HashBlock(a), HashBlock(b)) > 0 {
+		a, b = b, a
+	}
+	return &proto.Evidence{
+		First:  SignedHeaderOf(a),
+		Second: SignedHeaderOf(b),
+	}
+}
+
+// EvidenceHash identifies ev, the relaying peer is not part of it.
+func EvidenceHash(ev *proto.Evidence) []byte {
+	unsigned := pb.Clone(ev).(*proto.Evidence)
+	unsigned.ListenAddr = ""
+	b, err := pb.Marshal(unsigned)
+	if err != nil {
+		panic(err)
+	}
+	hash := sha256.Sum256(b)
+	return hash[:]
+}
+
+// HashEvidence returns the hash a block header commits to its evidence
+// with, nil without evidence.
+func HashEvidence(evidence []*proto.Evidence) []byte {
+	if len(evidence) == 0 {
+		return nil
+	}
+	hash := sha256.New()
+	for _, ev := range evidence {
+		hash.Write(EvidenceHash(ev))
+	}
+	return hash.Sum(nil)
+}
+
+// VerifyEvidence checks that ev holds two different headers of the same
+// height signed by the same key.
+func VerifyEvidence(ev *proto.Evidence) error {
+	if ev.First == nil || ev.Second == nil || ev.First.Header == nil || ev.Second.Header == nil {
+		return fmt.Errorf("evidence without two headers")
+	}
+	if ev.First.Header.Height != ev.Second.Header.Height {
+		return fmt.Errorf("evidence headers of different heights")
+	}
+	if !bytes.Equal(ev.First.PublicKey, ev.Second.PublicKey) {
+		return fmt.Errorf("evidence headers of different signers")
+	}
+	if bytes.Equal(HashHeader(ev.First.Header), HashHeader(ev.Second.Header)) {
+		return fmt.Errorf("evidence headers are the same")
+	}
+	for _, sh := range []*proto.SignedHeader{ev.First, ev.Second} {
+		if len(sh.PublicKey) != crypto.PubKeyLen || len(sh.Signature) != crypto.SignatureLen {
+			return fmt.Errorf("evidence header is not signed")
+		}
+		sig := crypto.SignatureFromBytes(sh.Signature)
+		if !sig.Verify(crypto.PublicKeyFromBytes(sh.PublicKey), HashHeader(sh.Header)) {
+			return fmt.Errorf("invalid evidence header signature")
+		}
+	}
+	return nil
+}
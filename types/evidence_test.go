@@ -0,0 +1,59 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/64bitAryan/blocker/crypto"
+	"github.com/64bitAryan/blocker/proto"
+	"github.com/64bitAryan/blocker/util"
+	"github.com/stretchr/testify/assert"
+	pb "google.golang.org/protobuf/proto"
+)
+
+func equivocation(privKey *crypto.PrivateKeys) (*proto.Block, *proto.Block) {
+	a, b := util.RandomBlock(), util.RandomBlock()
+	b.Header.Height = a.Header.Height
+	SignBlock(privKey, a)
+	SignBlock(privKey, b)
+	return a, b
+}
+
+func TestVerifyEvidence(t *testing.T) {
+	privKey := crypto.GeneratePrivateKey()
+	a, b := equivocation(privKey)
+	ev := NewEvidence(a, b)
+	assert.Nil(t, VerifyEvidence(ev))
+	assert.Equal(t, EvidenceHash(ev), EvidenceHash(NewEvidence(b, a)))
+
+	ev.ListenAddr = ":3000"
+	assert.Equal(t, EvidenceHash(NewEvidence(a, b)), EvidenceHash(ev))
+
+	assert.NotNil(t, VerifyEvidence(NewEvidence(a, a)))
+	assert.NotNil(t, VerifyEvidence(&proto.Evidence{}))
+
+	other := pb.Clone(b).(*proto.Block)
+	SignBlock(crypto.GeneratePrivateKey(), other)
+	assert.ErrorContains(t, VerifyEvidence(NewEvidence(a, other)), "signers")
+
+	other = pb.Clone(b).(*proto.Block)
+	other.Header.Height++
+	SignBlock(privKey, other)
+	assert.ErrorContains(t, VerifyEvidence(NewEvidence(a, other)), "heights")
+
+	forged := NewEvidence(a, b)
+	forged.Second.Header.Timestamp++
+	assert.ErrorContains(t, VerifyEvidence(forged), "signature")
+}
+
+func TestBlockCommitsToEvidence(t *testing.T) {
+	privKey := crypto.GeneratePrivateKey()
+	a, b := equivocation(privKey)
+	block := util.RandomBlock()
+	block.Evidence = []*proto.Evidence{NewEvidence(a, b)}
+	SignBlock(privKey, block)
+	assert.Len(t, block.Header.EvidenceHash, 32)
+	assert.True(t, VerifyBlock(block))
+
+	block.Evidence = nil
+	assert.False(t, VerifyBlock(block))
+}
@@ -0,0 +1,69 @@
+package types
+
+import (
+	"crypto/sha256"
+
+	"github.com/64bitAryan/blocker/crypto"
+	"github.com/64bitAryan/blocker/proto"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// ProposalID identifies a validator set change, approvals refer to it.
+func ProposalID(change *proto.ValidatorChange) []byte {
+	b, err := pb.Marshal(change)
+	if err != nil {
+		panic(err)
+	}
+	hash := sha256.Sum256(b)
+	return hash[:]
+}
+
+// NewValidatorProposal returns a governance transaction of pk proposing
+// change, which also counts as the approval of pk.
+func NewValidatorProposal(pk *crypto.PrivateKeys, change *proto.ValidatorChange) *proto.Transaction {
+	tx := &proto.Transaction{
+		Version: 1,
+		Governance: &proto.Governance{
+			Change: change,
+		},
+	}
+	SignGovernance(pk, tx)
+	return tx
+}
+
+// NewValidatorApproval returns a governance transaction of pk approving the
+// proposal with the given id.
+func NewValidatorApproval(pk *crypto.PrivateKeys, proposal []byte) *proto.Transaction {
+	tx := &proto.Transaction{
+		Version: 1,
+		Governance: &proto.Governance{
+			Proposal: proposal,
+		},
+	}
+	SignGovernance(pk, tx)
+	return tx
+}
+
+// GovernanceHash returns the hash the signer of a governance transaction
+// signs, which is the hash of the transaction without the signature.
+func GovernanceHash(tx *proto.Transaction) []byte {
+	stripped := pb.Clone(tx).(*proto.Transaction)
+	stripped.Governance.Signature = nil
+	return HashTransaction(stripped)
+}
+
+func SignGovernance(pk *crypto.PrivateKeys, tx *proto.Transaction) *crypto.Signature {
+	tx.Governance.PublicKey = pk.Public().Bytes()
+	sig := pk.Sign(GovernanceHash(tx))
+	tx.Governance.Signature = sig.Bytes()
+	return sig
+}
+
+func VerifyGovernance(tx *proto.Transaction) bool {
+	gov := tx.Governance
+	if gov == nil || len(gov.PublicKey) != crypto.PubKeyLen || len(gov.Signature) != crypto.SignatureLen {
+		return false
+	}
+	sig := crypto.SignatureFromBytes(gov.Signature)
+	return sig.Verify(crypto.PublicKeyFromBytes(gov.PublicKey), GovernanceHash(tx))
+}
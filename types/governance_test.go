@@ -0,0 +1,43 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/64bitAryan/blocker/crypto"
+	"github.com/64bitAryan/blocker/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGovernanceSignature(t *testing.T) {
+	var (
+		privKey = crypto.GeneratePrivateKey()
+		change  = &proto.ValidatorChange{
+			Action:    proto.ValidatorChange_ADD,
+			PublicKey: crypto.GeneratePrivateKey().Public().Bytes(),
+		}
+	)
+	proposal := NewValidatorProposal(privKey, change)
+	assert.True(t, VerifyGovernance(proposal))
+	assert.Equal(t, privKey.Public().Bytes(), proposal.Governance.PublicKey)
+
+	approval := NewValidatorApproval(crypto.GeneratePrivateKey(), ProposalID(change))
+	assert.True(t, VerifyGovernance(approval))
+
+	approval.Governance.Proposal = ProposalID(&proto.ValidatorChange{})
+	assert.False(t, VerifyGovernance(approval))
+	assert.False(t, VerifyGovernance(&proto.Transaction{}))
+}
+
+func TestProposalID(t *testing.T) {
+	change := &proto.ValidatorChange{
+		Action:    proto.ValidatorChange_REMOVE,
+		PublicKey: crypto.GeneratePrivateKey().Public().Bytes(),
+	}
+	assert.Equal(t, ProposalID(change), ProposalID(change))
+	other := &proto.ValidatorChange{
+		Action:    change.Action,
+		PublicKey: change.PublicKey,
+		Nonce:     1,
+	}
+	assert.NotEqual(t, ProposalID(change), ProposalID(other))
+}
@@ -9,7 +9,7 @@ import (
 )
 
 func SignTransaction(pk *crypto.PrivateKeys, tx *proto.Transaction) *crypto.Signature {
-	return pk.Sign(HashTransaction(tx))
+	return pk.Sign(SigHash(tx))
 }
 
 func HashTransaction(tx *proto.Transaction) []byte {
@@ -21,7 +21,19 @@ func HashTransaction(tx *proto.Transaction) []byte {
 	return hash[:]
 }
 
+// SigHash returns the hash the inputs of tx are signed over, which is the
+// hash of the transaction with all signatures and unlock scripts removed.
+func SigHash(tx *proto.Transaction) []byte {
+	stripped := pb.Clone(tx).(*proto.Transaction)
+	for _, input := range stripped.Inputs {
+		input.Signature = nil
+		input.UnlockScript = nil
+	}
+	return HashTransaction(stripped)
+}
+
 func VerifyTransaction(tx *proto.Transaction) bool {
+	hash := SigHash(tx)
 	for _, inputs := range tx.Inputs {
 		if len(inputs.Signature) == 0 {
 			panic("the transaction has no signature")
@@ -29,9 +41,7 @@ func VerifyTransaction(tx *proto.Transaction) bool {
 
 		sig := crypto.SignatureFromBytes(inputs.Signature)
 		pubKey := crypto.PublicKeyFromBytes(inputs.PublicKey)
-		// TODO: we have set the signature to nil,fix this
-		inputs.Signature = nil
-		if !sig.Verify(pubKey, HashTransaction(tx)) {
+		if !sig.Verify(pubKey, hash) {
 			return false
 		}
 	}
@@ -0,0 +1,43 @@
+package types
+
+import (
+	"crypto/sha256"
+
+	"github.com/64bitAryan/blocker/crypto"
+	"github.com/64bitAryan/blocker/proto"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// keeps vote signatures from being valid for anything else
+var voteDomain = []byte("blocker-vote")
+
+// VoteHash returns the hash a validator signs for v, the relaying peer is
+// not part of it.
+func VoteHash(v *proto.Vote) []byte {
+	unsigned := pb.Clone(v).(*proto.Vote)
+	unsigned.Signature = nil
+	unsigned.ListenAddr = ""
+	b, err := pb.Marshal(unsigned)
+	if err != nil {
+		panic(err)
+	}
+	hash := sha256.New()
+	hash.Write(voteDomain)
+	hash.Write(b)
+	return hash.Sum(nil)
+}
+
+func SignVote(pk *crypto.PrivateKeys, v *proto.Vote) *crypto.Signature {
+	v.PublicKey = pk.Public().Bytes()
+	sig := pk.Sign(VoteHash(v))
+	v.Signature = sig.Bytes()
+	return sig
+}
+
+func VerifyVote(v *proto.Vote) bool {
+	if len(v.PublicKey) != crypto.PubKeyLen || len(v.Signature) != crypto.SignatureLen {
+		return false
+	}
+	sig := crypto.SignatureFromBytes(v.Signature)
+	return sig.Verify(crypto.PublicKeyFromBytes(v.PublicKey), VoteHash(v))
+}
@@ -0,0 +1,29 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/64bitAryan/blocker/crypto"
+	"github.com/64bitAryan/blocker/proto"
+	"github.com/64bitAryan/blocker/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignVote(t *testing.T) {
+	v := &proto.Vote{
+		Type:      proto.VoteType_PRECOMMIT,
+		Height:    10,
+		Round:     3,
+		BlockHash: util.RandomHash(),
+	}
+	SignVote(crypto.GeneratePrivateKey(), v)
+	assert.True(t, VerifyVote(v))
+
+	// relaying does not invalidate the vote
+	v.ListenAddr = ":3000"
+	assert.True(t, VerifyVote(v))
+
+	v.Type = proto.VoteType_PREVOTE
+	assert.False(t, VerifyVote(v))
+	assert.False(t, VerifyVote(&proto.Vote{}))
+}